@@ -0,0 +1,78 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// webmCluster é o ID EBML do Cluster: tudo antes dele é cabeçalho (EBML, Segment, Tracks)
+var webmCluster = []byte{0x1F, 0x43, 0xB6, 0x75}
+
+// StreamStart devolve a codificação quando o chunk abre um fluxo novo (traz cabeçalho).
+// No Ogg toda página começa com "OggS"; só a primeira tem a flag de início (BOS).
+func StreamStart(data []byte) string {
+	encoding := Sniff(data)
+	if encoding == EncodingOgg && (len(data) < 6 || data[5]&0x02 == 0) {
+		return ""
+	}
+	return encoding
+}
+
+// SplitHeader separa o cabeçalho de inicialização de um container do áudio que vem depois.
+// O MediaRecorder só manda esse cabeçalho no primeiro chunk; os seguintes só decodificam
+// com ele na frente. Retorna header vazio para codificações sem cabeçalho separável.
+func SplitHeader(encoding string, data []byte) (header, rest []byte) {
+	n := 0
+	switch encoding {
+	case EncodingWebM:
+		n = bytes.Index(data, webmCluster)
+		if n < 0 {
+			n = len(data) // Só cabeçalho, sem áudio ainda
+		}
+	case EncodingOgg:
+		n = oggHeaderLen(data)
+	case EncodingFLAC:
+		n = flacHeaderLen(data)
+	}
+	return data[:n], data[n:]
+}
+
+// oggHeaderLen soma as páginas iniciais com granule position zero (OpusHead/OpusTags, Vorbis)
+func oggHeaderLen(data []byte) int {
+	n := 0
+	for len(data)-n >= 27 && string(data[n:n+4]) == "OggS" {
+		segments := int(data[n+26])
+		if len(data)-n < 27+segments {
+			break
+		}
+		size := 27 + segments
+		for _, lacing := range data[n+27 : n+27+segments] {
+			size += int(lacing)
+		}
+		if binary.LittleEndian.Uint64(data[n+6:n+14]) != 0 || len(data)-n < size {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+// flacHeaderLen pula "fLaC" e os blocos de metadados até o último (bit 7 do tipo)
+func flacHeaderLen(data []byte) int {
+	if !bytes.HasPrefix(data, []byte("fLaC")) {
+		return 0
+	}
+	n := 4
+	for len(data)-n >= 4 {
+		last := data[n]&0x80 != 0
+		size := int(data[n+1])<<16 | int(data[n+2])<<8 | int(data[n+3])
+		n += 4 + size
+		if n > len(data) {
+			return len(data)
+		}
+		if last {
+			break
+		}
+	}
+	return n
+}
@@ -0,0 +1,104 @@
+package audio
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Codificações aceitas vindas do cliente
+const (
+	EncodingPCM16 = "pcm16" // PCM little-endian 16 bits (padrão do studio.html)
+	EncodingWAV   = "wav"
+	EncodingWebM  = "webm" // webm/opus do MediaRecorder
+	EncodingOgg   = "ogg"  // ogg/opus
+	EncodingFLAC  = "flac"
+)
+
+// Format descreve o áudio que um cliente envia pelo WebSocket
+type Format struct {
+	Encoding   string `json:"encoding"`
+	SampleRate int    `json:"sample_rate"`
+	Channels   int    `json:"channels"`
+}
+
+// DefaultFormat é o que o studio.html envia quando não negocia nada
+func DefaultFormat() Format {
+	return Format{Encoding: EncodingPCM16, SampleRate: 16000, Channels: 1}
+}
+
+// Negotiate valida o formato pedido pelo cliente, completando o que faltar com o padrão
+func Negotiate(encoding string, sampleRate, channels int) (Format, error) {
+	f := DefaultFormat()
+	if encoding != "" {
+		f.Encoding = strings.ToLower(encoding)
+	}
+	switch f.Encoding {
+	case EncodingPCM16, EncodingWAV, EncodingWebM, EncodingOgg, EncodingFLAC:
+	case "opus":
+		f.Encoding = EncodingWebM
+	default:
+		return f, fmt.Errorf("codificação de áudio não suportada: %s", encoding)
+	}
+
+	if sampleRate != 0 {
+		if sampleRate < 8000 || sampleRate > 48000 {
+			return f, fmt.Errorf("sample rate fora do intervalo (8000-48000): %d", sampleRate)
+		}
+		f.SampleRate = sampleRate
+	}
+	if channels != 0 {
+		if channels < 1 || channels > 2 {
+			return f, fmt.Errorf("número de canais inválido: %d", channels)
+		}
+		f.Channels = channels
+	}
+	return f, nil
+}
+
+// IsPCM indica se os frames são amostras cruas (e podem passar pelo VAD)
+func (f Format) IsPCM() bool {
+	return f.Encoding == EncodingPCM16
+}
+
+// MIMEType retorna o tipo que o modelo deve receber para esta codificação
+func (f Format) MIMEType() string {
+	switch f.Encoding {
+	case EncodingWebM:
+		return "audio/webm"
+	case EncodingOgg:
+		return "audio/ogg"
+	case EncodingFLAC:
+		return "audio/flac"
+	default:
+		// PCM cru é embrulhado em WAV antes de sair
+		return "audio/wav"
+	}
+}
+
+// Encode prepara o frame recebido para envio ao modelo, devolvendo o blob e o MIME.
+// PCM vira WAV; containers reais são detectados pelo cabeçalho e enviados como estão.
+func (f Format) Encode(data []byte) ([]byte, string) {
+	if sniffed := Sniff(data); sniffed != "" {
+		return data, Format{Encoding: sniffed}.MIMEType()
+	}
+	if f.IsPCM() {
+		return EncodeWAV(data, f.SampleRate, f.Channels), f.MIMEType()
+	}
+	return data, f.MIMEType()
+}
+
+// Sniff identifica containers conhecidos pelos bytes mágicos do cabeçalho
+func Sniff(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("RIFF")) && len(data) >= 12 && string(data[8:12]) == "WAVE":
+		return EncodingWAV
+	case bytes.HasPrefix(data, []byte("fLaC")):
+		return EncodingFLAC
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return EncodingOgg
+	case bytes.HasPrefix(data, []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return EncodingWebM
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package audio
+
+import (
+	"encoding/binary"
+)
+
+const wavHeaderSize = 44
+
+// EncodeWAV embrulha amostras PCM16 little-endian num container WAV válido
+func EncodeWAV(pcm []byte, sampleRate, channels int) []byte {
+	// Descarta byte solto: PCM16 precisa de amostras completas
+	if len(pcm)%2 != 0 {
+		pcm = pcm[:len(pcm)-1]
+	}
+
+	const bitsPerSample = 16
+	blockAlign := channels * bitsPerSample / 8
+	byteRate := sampleRate * blockAlign
+
+	out := make([]byte, wavHeaderSize+len(pcm))
+	copy(out[0:], "RIFF")
+	binary.LittleEndian.PutUint32(out[4:], uint32(36+len(pcm)))
+	copy(out[8:], "WAVE")
+
+	// Chunk "fmt " (PCM = formato 1)
+	copy(out[12:], "fmt ")
+	binary.LittleEndian.PutUint32(out[16:], 16)
+	binary.LittleEndian.PutUint16(out[20:], 1)
+	binary.LittleEndian.PutUint16(out[22:], uint16(channels))
+	binary.LittleEndian.PutUint32(out[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(out[28:], uint32(byteRate))
+	binary.LittleEndian.PutUint16(out[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(out[34:], bitsPerSample)
+
+	// Chunk "data"
+	copy(out[36:], "data")
+	binary.LittleEndian.PutUint32(out[40:], uint32(len(pcm)))
+	copy(out[wavHeaderSize:], pcm)
+
+	return out
+}
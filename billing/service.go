@@ -0,0 +1,224 @@
+package billing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"k-lens/models"
+	"log"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tipos de evento aceitos no webhook
+const (
+	EventCreated   = "subscription.created"
+	EventRenewed   = "subscription.renewed"
+	EventPastDue   = "subscription.past_due"
+	EventCancelled = "subscription.cancelled"
+)
+
+// ErrDuplicate indica que o evento já foi processado (o webhook deve responder 200 mesmo assim)
+var ErrDuplicate = errors.New("evento já processado")
+
+// ErrInvalid marca webhooks recusados pela assinatura ou pelo payload (o webhook responde 400);
+// os demais erros de Handle são falhas nossas e o gateway deve tentar de novo
+var ErrInvalid = errors.New("webhook inválido")
+
+// ErrUnknownUser indica uma assinatura sem usuário no k-lens. O evento fica registrado
+// e o webhook responde 200: reenviar não vai fazer o usuário aparecer.
+var ErrUnknownUser = errors.New("usuário da assinatura não encontrado")
+
+// Event é o payload normalizado do webhook:
+//
+//	{"id": "evt_1", "type": "subscription.renewed", "created": 1700000000,
+//	 "data": {"subscription_id": "sub_1", "customer_email": "a@b.com", "current_period_end": 1702592000}}
+type Event struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Created int64  `json:"created"`
+	Data    struct {
+		SubscriptionID   string `json:"subscription_id"`
+		CustomerEmail    string `json:"customer_email"`
+		CurrentPeriodEnd int64  `json:"current_period_end"`
+	} `json:"data"`
+}
+
+func ParseEvent(body []byte) (*Event, error) {
+	var ev Event
+	if err := json.Unmarshal(body, &ev); err != nil {
+		return nil, fmt.Errorf("payload inválido: %v", err)
+	}
+	if ev.ID == "" || ev.Data.SubscriptionID == "" {
+		return nil, errors.New("evento sem id ou subscription_id")
+	}
+	// Sem created o evento viraria 1970 e seria descartado como fora de ordem
+	if ev.Created <= 0 {
+		return nil, errors.New("evento sem created")
+	}
+	switch ev.Type {
+	case EventCreated, EventRenewed, EventPastDue, EventCancelled:
+	default:
+		return nil, fmt.Errorf("tipo de evento não suportado: %s", ev.Type)
+	}
+	return &ev, nil
+}
+
+// Service aplica eventos de cobrança no User.IsVIP, com carência para pagamentos atrasados
+type Service struct {
+	DB          *gorm.DB
+	Verifier    Verifier
+	GracePeriod time.Duration
+	now         func() time.Time
+}
+
+func NewService(db *gorm.DB, verifier Verifier, grace time.Duration) *Service {
+	return &Service{DB: db, Verifier: verifier, GracePeriod: grace, now: time.Now}
+}
+
+// Handle verifica, interpreta e aplica um webhook. É idempotente pelo ID do evento.
+func (s *Service) Handle(header http.Header, body []byte) (*Event, error) {
+	if err := s.Verifier.Verify(header, body); err != nil {
+		return nil, fmt.Errorf("%w: assinatura rejeitada: %w", ErrInvalid, err)
+	}
+	ev, err := ParseEvent(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+
+	var unknown bool
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		tx.Model(&models.SubscriptionHistory{}).Where("event_id = ?", ev.ID).Count(&count)
+		if count > 0 {
+			return ErrDuplicate
+		}
+		var err error
+		unknown, err = s.apply(tx, ev)
+		return err
+	})
+	if err == nil && unknown {
+		err = ErrUnknownUser
+	}
+	return ev, err
+}
+
+// apply grava o evento no histórico e atualiza o usuário. unknown indica que o
+// evento foi só registrado porque nenhum usuário tem a assinatura (nem o email).
+func (s *Service) apply(tx *gorm.DB, ev *Event) (unknown bool, err error) {
+	occurred := time.Unix(ev.Created, 0)
+	periodEnd := time.Unix(ev.Data.CurrentPeriodEnd, 0)
+	if ev.Data.CurrentPeriodEnd == 0 {
+		periodEnd = time.Time{}
+	}
+
+	history := models.SubscriptionHistory{
+		EventID:        ev.ID,
+		EventType:      ev.Type,
+		SubscriptionID: ev.Data.SubscriptionID,
+		Status:         statusFor(ev.Type),
+		PeriodEnd:      periodEnd,
+		OccurredAt:     occurred,
+	}
+
+	var user models.User
+	err = tx.Where("subscription_id = ?", ev.Data.SubscriptionID).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) && ev.Data.CustomerEmail != "" {
+		err = tx.Where("email = ?", ev.Data.CustomerEmail).First(&user).Error
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Registra sem aplicar: o reenvio vira duplicado em vez de repetir para sempre
+		return true, tx.Create(&history).Error
+	}
+	if err != nil {
+		return false, fmt.Errorf("erro ao buscar usuário da assinatura %s: %v", ev.Data.SubscriptionID, err)
+	}
+	history.UserID = user.ID
+
+	// Evento fora de ordem (ex: "past_due" antigo chegando depois do "renewed"): só registra
+	var newer int64
+	tx.Model(&models.SubscriptionHistory{}).
+		Where("subscription_id = ? AND applied = ? AND occurred_at > ?", ev.Data.SubscriptionID, true, occurred).
+		Count(&newer)
+	if newer > 0 {
+		log.Printf("↩️ [Billing] Evento %s fora de ordem, registrado sem aplicar", ev.ID)
+		return false, tx.Create(&history).Error
+	}
+
+	now := s.now()
+	until := s.vipUntil(ev.Type, now, periodEnd)
+	user.SubscriptionID = ev.Data.SubscriptionID
+	user.VIPUntil = &until
+	user.IsVIP = until.After(now)
+
+	history.Applied = true
+	if err := tx.Create(&history).Error; err != nil {
+		return false, err
+	}
+	if err := tx.Model(&user).Select("SubscriptionID", "VIPUntil", "IsVIP").Updates(&user).Error; err != nil {
+		return false, err
+	}
+	log.Printf("💳 [Billing] %s: user %d VIP=%v até %s", ev.Type, user.ID, user.IsVIP, until.Format(time.RFC3339))
+	return false, nil
+}
+
+// vipUntil decide até quando o usuário fica VIP após o evento
+func (s *Service) vipUntil(eventType string, now, periodEnd time.Time) time.Time {
+	switch eventType {
+	case EventCreated, EventRenewed:
+		// Carência cobre a renovação que chega atrasada do gateway
+		if periodEnd.IsZero() {
+			return now.Add(30*24*time.Hour + s.GracePeriod)
+		}
+		return periodEnd.Add(s.GracePeriod)
+	case EventPastDue:
+		return now.Add(s.GracePeriod)
+	default: // cancelado: mantém até o fim do período já pago
+		if periodEnd.After(now) {
+			return periodEnd
+		}
+		return now
+	}
+}
+
+func statusFor(eventType string) string {
+	switch eventType {
+	case EventPastDue:
+		return models.SubscriptionPastDue
+	case EventCancelled:
+		return models.SubscriptionCancelled
+	default:
+		return models.SubscriptionActive
+	}
+}
+
+// ExpireLoop derruba o IsVIP de quem passou do VIPUntil (carência vencida, cancelamento efetivado)
+func (s *Service) ExpireLoop(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		s.expire()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) expire() {
+	// IsVIP é a coluna is_v_ip (nome padrão do GORM desde a primeira versão do User)
+	res := s.DB.Model(&models.User{}).
+		Where("is_v_ip = ? AND vip_until IS NOT NULL AND vip_until < ?", true, s.now()).
+		Update("IsVIP", false)
+	if res.Error != nil {
+		log.Printf("⚠️ [Billing] Erro ao expirar VIPs: %v", res.Error)
+		return
+	}
+	if res.RowsAffected > 0 {
+		log.Printf("⌛ [Billing] %d assinaturas VIP expiradas", res.RowsAffected)
+	}
+}
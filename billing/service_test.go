@@ -0,0 +1,173 @@
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"k-lens/db/dbtest"
+	"k-lens/models"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("whsec_teste")
+
+// testService monta o serviço com banco em memória e relógio fixo
+func testService(t *testing.T, now time.Time) *Service {
+	t.Helper()
+	s := NewService(dbtest.Open(t, &models.User{}, &models.SubscriptionHistory{}), NewHMACVerifier(string(testSecret)), 72*time.Hour)
+	s.now = func() time.Time { return now }
+	s.Verifier.(*HMACVerifier).now = s.now
+	return s
+}
+
+func eventBody(id, eventType string, created time.Time, periodEnd time.Time) []byte {
+	return []byte(fmt.Sprintf(`{"id":%q,"type":%q,"created":%d,"data":{"subscription_id":"sub_1","customer_email":"army@klens.test","current_period_end":%d}}`,
+		id, eventType, created.Unix(), periodEnd.Unix()))
+}
+
+// signed monta o header com a assinatura v1 gerada por Sign no instante at
+func signed(at time.Time, body []byte) http.Header {
+	h := http.Header{}
+	h.Set(SignatureHeader, fmt.Sprintf("t=%d,v1=%s", at.Unix(), Sign(testSecret, at.Unix(), body)))
+	return h
+}
+
+func TestHandleRejectsBadSignatures(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	s := testService(t, now)
+	body := eventBody("evt_1", EventRenewed, now, now.Add(30*24*time.Hour))
+
+	forged := http.Header{}
+	forged.Set(SignatureHeader, fmt.Sprintf("t=%d,v1=%s", now.Unix(), Sign([]byte("outro_segredo"), now.Unix(), body)))
+	cases := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		want   string
+	}{
+		{"segredo errado", forged, body, "não confere"},
+		{"corpo alterado", signed(now, body), []byte(strings.Replace(string(body), "sub_1", "sub_2", 1)), "não confere"},
+		{"timestamp velho", signed(now.Add(-10*time.Minute), body), body, "tolerância"},
+		{"timestamp no futuro", signed(now.Add(10*time.Minute), body), body, "tolerância"},
+		{"sem header", http.Header{}, body, "ausente"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.Handle(tc.header, tc.body)
+			if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("erro %v, esperava %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestHandleAppliesEventOnce(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	s := testService(t, now)
+	s.DB.Create(&models.User{GoogleID: "g-1", Email: "army@klens.test"})
+
+	periodEnd := now.Add(30 * 24 * time.Hour)
+	body := eventBody("evt_1", EventCreated, now, periodEnd)
+	if _, err := s.Handle(signed(now, body), body); err != nil {
+		t.Fatal(err)
+	}
+	var user models.User
+	s.DB.First(&user)
+	if !user.IsVIP || user.SubscriptionID != "sub_1" || !user.VIPUntil.Equal(periodEnd.Add(72*time.Hour)) {
+		t.Fatalf("assinatura não aplicada: %+v", user)
+	}
+
+	// Reenvio do gateway (assinatura nova, mesmo event_id)
+	if _, err := s.Handle(signed(now.Add(time.Minute), body), body); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("reenvio deveria ser duplicado: %v", err)
+	}
+	var count int64
+	s.DB.Model(&models.SubscriptionHistory{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("histórico com %d eventos, esperava 1", count)
+	}
+}
+
+func TestHandleRecordsOutOfOrderEventWithoutApplying(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	s := testService(t, now)
+	s.DB.Create(&models.User{GoogleID: "g-1", Email: "army@klens.test", SubscriptionID: "sub_1"})
+
+	periodEnd := now.Add(30 * 24 * time.Hour)
+	renewed := eventBody("evt_2", EventRenewed, now.Add(-time.Hour), periodEnd)
+	if _, err := s.Handle(signed(now, renewed), renewed); err != nil {
+		t.Fatal(err)
+	}
+	// past_due antigo chegando depois do renewed
+	pastDue := eventBody("evt_1", EventPastDue, now.Add(-2*time.Hour), time.Time{})
+	if _, err := s.Handle(signed(now, pastDue), pastDue); err != nil {
+		t.Fatal(err)
+	}
+
+	var user models.User
+	s.DB.First(&user)
+	if !user.IsVIP || !user.VIPUntil.Equal(periodEnd.Add(72*time.Hour)) {
+		t.Fatalf("evento antigo não pode encurtar o VIP: %+v", user)
+	}
+	var history models.SubscriptionHistory
+	s.DB.Where("event_id = ?", "evt_1").First(&history)
+	if history.ID == 0 || history.Applied {
+		t.Fatalf("evento fora de ordem deveria ser registrado sem aplicar: %+v", history)
+	}
+}
+
+func TestHandleAcknowledgesUnknownUser(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	s := testService(t, now)
+
+	body := eventBody("evt_1", EventCreated, now, now.Add(30*24*time.Hour))
+	if _, err := s.Handle(signed(now, body), body); !errors.Is(err, ErrUnknownUser) {
+		t.Fatalf("esperava ErrUnknownUser: %v", err)
+	}
+	// O evento fica registrado: o reenvio é tratado como duplicado
+	if _, err := s.Handle(signed(now, body), body); !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("reenvio deveria ser duplicado: %v", err)
+	}
+}
+
+func TestParseEventRequiresCreated(t *testing.T) {
+	body := []byte(`{"id":"evt_1","type":"subscription.renewed","data":{"subscription_id":"sub_1"}}`)
+	if _, err := ParseEvent(body); err == nil || !strings.Contains(err.Error(), "created") {
+		t.Fatalf("evento sem created deveria ser recusado: %v", err)
+	}
+}
+
+func TestHandleSeparatesInvalidFromInternalErrors(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	s := testService(t, now)
+
+	malformed := []byte(`{"id":"evt_1"}`)
+	if _, err := s.Handle(signed(now, malformed), malformed); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("payload malformado deveria ser ErrInvalid: %v", err)
+	}
+
+	// Banco fora do ar não é culpa do gateway
+	s.DB.Migrator().DropTable(&models.SubscriptionHistory{})
+	body := eventBody("evt_2", EventRenewed, now, now.Add(time.Hour))
+	if _, err := s.Handle(signed(now, body), body); err == nil || errors.Is(err, ErrInvalid) {
+		t.Fatalf("falha do banco não pode virar ErrInvalid: %v", err)
+	}
+}
+
+func TestExpireDropsLapsedVIP(t *testing.T) {
+	now := time.Unix(1_800_000_000, 0)
+	s := testService(t, now)
+	lapsed, current := now.Add(-time.Hour), now.Add(time.Hour)
+	s.DB.Create(&models.User{GoogleID: "g-1", Email: "a@klens.test", IsVIP: true, VIPUntil: &lapsed})
+	s.DB.Create(&models.User{GoogleID: "g-2", Email: "b@klens.test", IsVIP: true, VIPUntil: &current})
+
+	s.expire()
+
+	var users []models.User
+	s.DB.Order("id").Find(&users)
+	if users[0].IsVIP || !users[1].IsVIP {
+		t.Fatalf("só o VIP vencido deveria cair: %v, %v", users[0].IsVIP, users[1].IsVIP)
+	}
+}
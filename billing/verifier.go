@@ -0,0 +1,83 @@
+package billing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carrega "t=<unix>,v1=<hmac-sha256 hex de "<t>.<body>">"
+const SignatureHeader = "X-Billing-Signature"
+
+// Verifier confere a autenticidade de um webhook antes de qualquer processamento.
+// Cada gateway (Stripe, MercadoPago...) pode ter o seu; HMACVerifier cobre o formato genérico.
+type Verifier interface {
+	Verify(header http.Header, body []byte) error
+}
+
+// HMACVerifier valida a assinatura HMAC com tolerância de relógio contra replay
+type HMACVerifier struct {
+	Secret    []byte
+	Tolerance time.Duration
+	now       func() time.Time
+}
+
+func NewHMACVerifier(secret string) *HMACVerifier {
+	return &HMACVerifier{Secret: []byte(secret), Tolerance: 5 * time.Minute, now: time.Now}
+}
+
+func (v *HMACVerifier) Verify(header http.Header, body []byte) error {
+	if len(v.Secret) == 0 {
+		return errors.New("segredo do webhook não configurado")
+	}
+
+	var ts, sig string
+	for _, part := range strings.Split(header.Get(SignatureHeader), ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "t":
+			ts = kv[1]
+		case "v1":
+			sig = kv[1]
+		}
+	}
+	if ts == "" || sig == "" {
+		return errors.New("assinatura ausente ou malformada")
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("timestamp da assinatura inválido")
+	}
+	if age := v.now().Sub(time.Unix(unix, 0)); age > v.Tolerance || age < -v.Tolerance {
+		return fmt.Errorf("assinatura fora da janela de tolerância (%s)", age.Round(time.Second))
+	}
+
+	expected := Sign(v.Secret, unix, body)
+	if !hmac.Equal([]byte(expected), []byte(sig)) {
+		return errors.New("assinatura não confere")
+	}
+	return nil
+}
+
+// Sign gera a assinatura v1 (útil para fixtures assinadas localmente)
+func Sign(secret []byte, unix int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d.", unix)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignatureHeaderValue monta o valor completo do header para um corpo
+func SignatureHeaderValue(secret []byte, at time.Time, body []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", at.Unix(), Sign(secret, at.Unix(), body))
+}
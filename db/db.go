@@ -44,6 +44,15 @@ func InitDB() {
 		&models.User{},
 		&models.LiveArchive{},
 		&models.CaptionLog{},
+		&models.HighlightRule{},
+		&models.ClipJob{},
+		&models.Clip{},
+		&models.Session{},
+		&models.LiveMember{},
+		&models.SubscriptionHistory{},
+		&models.AccessInvite{},
+		&models.GlossaryTerm{},
+		&models.CaptionRevision{},
 	)
 	if err != nil {
 		log.Fatal("Erro ao sincronizar tabelas (AutoMigrate):", err)
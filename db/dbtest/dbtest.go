@@ -0,0 +1,34 @@
+// Package dbtest abre um banco SQLite em memória com as tabelas dos models,
+// para testar handlers e serviços sem subir um Postgres. O driver (mattn/go-sqlite3)
+// usa CGO: os testes que dependem daqui precisam de um compilador C.
+package dbtest
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+var seq atomic.Int64
+
+// Open cria um banco vazio (um por chamada) e roda o AutoMigrate dos models informados
+func Open(t testing.TB, models ...any) *gorm.DB {
+	t.Helper()
+	// cache=shared: as conexões do pool enxergam o mesmo banco em memória
+	dsn := fmt.Sprintf("file:dbtest_%d?mode=memory&cache=shared", seq.Add(1))
+	gdb, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("dbtest: %v", err)
+	}
+	if err := gdb.AutoMigrate(models...); err != nil {
+		t.Fatalf("dbtest: AutoMigrate: %v", err)
+	}
+
+	sqlDB, _ := gdb.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	return gdb
+}
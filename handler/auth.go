@@ -1,22 +1,55 @@
 package handler
 
 import (
-	"crypto/rand"
-	"encoding/base64"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"k-lens/db"
+	"k-lens/models"
+	"log"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
+	"gorm.io/gorm"
 )
 
-var googleOauthConfig = &oauth2.Config{
-	// O RedirectURL deve vir de uma variável de ambiente no Cloud Run
-	RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"), 
-	ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-	ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
-	Scopes:       []string{"https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"},
-	Endpoint:     google.Endpoint,
+const (
+	oauthStateCookie = "klens_oauth"
+	oauthStateTTL    = 10 * time.Minute
+	googleUserInfo   = "https://openidconnect.googleapis.com/v1/userinfo"
+)
+
+// oauthConfig é montado na hora (e não na inicialização do pacote) para enxergar o .env.
+// GOOGLE_AUTH_URL/GOOGLE_TOKEN_URL permitem apontar para um provedor OAuth falso local.
+func oauthConfig() *oauth2.Config {
+	endpoint := google.Endpoint
+	if v := os.Getenv("GOOGLE_AUTH_URL"); v != "" {
+		endpoint.AuthURL = v
+	}
+	if v := os.Getenv("GOOGLE_TOKEN_URL"); v != "" {
+		endpoint.TokenURL = v
+	}
+
+	return &oauth2.Config{
+		// O RedirectURL deve vir de uma variável de ambiente no Cloud Run
+		RedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
+		ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
+		ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+		Scopes:       []string{"openid", "https://www.googleapis.com/auth/userinfo.email", "https://www.googleapis.com/auth/userinfo.profile"},
+		Endpoint:     endpoint,
+	}
+}
+
+func userInfoURL() string {
+	if v := os.Getenv("GOOGLE_USERINFO_URL"); v != "" {
+		return v
+	}
+	return googleUserInfo
 }
 
 // SecurityMiddleware: O "Guarda de Elite" que protege suas rotas
@@ -27,13 +60,49 @@ func SecurityMiddleware(next http.Handler) http.Handler {
 		w.Header().Set("X-Content-Type-Options", "nosniff")
 		w.Header().Set("X-XSS-Protection", "1; mode=block")
 
-		// Proteção simples por Token para a apresentação (evita intrusos)
-		// Para o celular acessar, basta colocar ?token=SUA_CHAVE na URL
-		secretToken := os.Getenv("APP_SECRET_TOKEN")
-		if secretToken != "" {
-			token := r.URL.Query().Get("token")
-			if token != secretToken && r.URL.Path != "/auth/google/login" {
-				http.Error(w, "Acesso restrito: Token inválido ou ausente.", http.StatusUnauthorized)
+		// Login, webhooks (assinatura própria) e métricas (METRICS_TOKEN) não passam pelo controle de acesso
+		if strings.HasPrefix(r.URL.Path, "/auth/google/") || strings.HasPrefix(r.URL.Path, "/webhooks/") || r.URL.Path == "/metrics" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Token assinado (header, query ou cookie): inválido/expirado/revogado é recusado
+		var claims *AccessClaims
+		raw, fromCookie := tokenFromRequest(r)
+		if raw != "" {
+			var err error
+			claims, err = parseToken(raw)
+			switch {
+			case err != nil && fromCookie:
+				// Convite velho guardado no cookie não derruba quem tem sessão: só é descartado
+				http.SetCookie(w, &http.Cookie{Name: accessCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+			case err != nil:
+				http.Error(w, "Acesso restrito: "+err.Error()+".", http.StatusUnauthorized)
+				return
+			}
+		}
+		if claims != nil {
+			// Link de convite: guarda o token em cookie para o WebSocket e os fetch da página
+			if r.URL.Query().Get("token") == raw {
+				http.SetCookie(w, &http.Cookie{
+					Name:     accessCookie,
+					Value:    raw,
+					Path:     "/",
+					Expires:  time.Unix(claims.ExpiresAt, 0),
+					HttpOnly: true,
+					Secure:   r.TLS != nil,
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+			r = withClaims(r, claims)
+		} else if protectedPath(r.URL.Path) {
+			// API, WebSocket e downloads exigem sessão ou convite
+			if _, err := CurrentUser(r); err != nil {
+				msg := "Acesso restrito: faça login ou use um link de convite."
+				if raw != "" {
+					msg = "Acesso restrito: " + errBadToken.Error() + "."
+				}
+				http.Error(w, msg, http.StatusUnauthorized)
 				return
 			}
 		}
@@ -42,13 +111,163 @@ func SecurityMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// HandleGoogleLogin inicia o fluxo OAuth
+// HandleGoogleLogin inicia o fluxo OAuth com state e PKCE guardados num cookie assinado
 func HandleGoogleLogin(w http.ResponseWriter, r *http.Request) {
-	b := make([]byte, 16)
-	rand.Read(b)
-	state := base64.URLEncoding.EncodeToString(b)
+	state := randomToken(16)
+	verifier := oauth2.GenerateVerifier()
+	expires := time.Now().Add(oauthStateTTL)
 
-	// Em um sistema real, você salvaria esse 'state' em um cookie para validar no callback
-	url := googleOauthConfig.AuthCodeURL(state)
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    signValue(fmt.Sprintf("%s|%s|%d", state, verifier, expires.Unix())),
+		Path:     "/auth/google/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	url := oauthConfig().AuthCodeURL(state, oauth2.AccessTypeOnline, oauth2.S256ChallengeOption(verifier))
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
-}
\ No newline at end of file
+}
+
+// HandleGoogleCallback valida o state, troca o code (com o verifier PKCE),
+// busca o perfil, faz upsert do User e abre a sessão.
+func HandleGoogleCallback(w http.ResponseWriter, r *http.Request) {
+	if db.DB == nil {
+		http.Error(w, "Banco de dados indisponível", http.StatusServiceUnavailable)
+		return
+	}
+
+	verifier, err := checkOAuthState(r)
+	// O cookie de state é de uso único
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Value: "", Path: "/auth/google/", MaxAge: -1, HttpOnly: true})
+	if err != nil {
+		log.Printf("⚠️ [OAuth] Callback recusado: %v", err)
+		http.Error(w, "Login inválido ou expirado, tente novamente.", http.StatusBadRequest)
+		return
+	}
+
+	if e := r.URL.Query().Get("error"); e != "" {
+		http.Error(w, "Login cancelado: "+e, http.StatusUnauthorized)
+		return
+	}
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		http.Error(w, "Código de autorização ausente", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	conf := oauthConfig()
+	token, err := conf.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		log.Printf("❌ [OAuth] Erro na troca do code: %v", err)
+		http.Error(w, "Falha ao autenticar com Google", http.StatusBadGateway)
+		return
+	}
+
+	profile, err := fetchGoogleProfile(ctx, conf.Client(ctx, token))
+	if err != nil {
+		log.Printf("❌ [OAuth] Erro ao buscar perfil: %v", err)
+		http.Error(w, "Falha ao buscar perfil do Google", http.StatusBadGateway)
+		return
+	}
+
+	user, err := upsertGoogleUser(profile)
+	if err != nil {
+		log.Printf("❌ [OAuth] Erro ao salvar usuário: %v", err)
+		http.Error(w, "Erro ao salvar usuário", http.StatusInternalServerError)
+		return
+	}
+
+	if err := createSession(w, r, user.ID); err != nil {
+		log.Printf("❌ [OAuth] Erro ao criar sessão: %v", err)
+		http.Error(w, "Erro ao criar sessão", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("🔐 [OAuth] Login de %s (user %d)", user.Email, user.ID)
+	http.Redirect(w, r, "/studio.html", http.StatusSeeOther)
+}
+
+// checkOAuthState confere o cookie assinado contra o state da query e devolve o verifier PKCE
+func checkOAuthState(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(oauthStateCookie)
+	if err != nil {
+		return "", errors.New("cookie de state ausente")
+	}
+	value, ok := verifyValue(cookie.Value)
+	if !ok {
+		return "", errors.New("assinatura do state inválida")
+	}
+
+	parts := strings.Split(value, "|")
+	if len(parts) != 3 {
+		return "", errors.New("state malformado")
+	}
+	var expires int64
+	fmt.Sscan(parts[2], &expires)
+	if time.Now().Unix() > expires {
+		return "", errors.New("state expirado")
+	}
+	if parts[0] != r.URL.Query().Get("state") {
+		return "", errors.New("state não confere")
+	}
+	return parts[1], nil
+}
+
+type googleProfile struct {
+	Sub           string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	Picture       string `json:"picture"`
+}
+
+func fetchGoogleProfile(ctx context.Context, client *http.Client) (*googleProfile, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, userInfoURL(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("userinfo respondeu %d", resp.StatusCode)
+	}
+
+	var profile googleProfile
+	if err := json.NewDecoder(resp.Body).Decode(&profile); err != nil {
+		return nil, err
+	}
+	if profile.Sub == "" || profile.Email == "" {
+		return nil, errors.New("perfil sem sub/email")
+	}
+	if !profile.EmailVerified {
+		return nil, errors.New("email não verificado no Google")
+	}
+	return &profile, nil
+}
+
+// upsertGoogleUser encontra o usuário pelo GoogleID (ou Email) e atualiza o perfil
+func upsertGoogleUser(p *googleProfile) (*models.User, error) {
+	var user models.User
+	err := db.DB.Where("google_id = ?", p.Sub).Or("email = ?", p.Email).First(&user).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	user.GoogleID = p.Sub
+	user.Email = p.Email
+	user.Name = p.Name
+	user.Avatar = p.Picture
+	if err := db.DB.Save(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"k-lens/db"
+	"k-lens/db/dbtest"
+	"k-lens/models"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGoogle é um provedor OAuth local: confere o PKCE na troca do code e devolve o perfil configurado
+type fakeGoogle struct {
+	*httptest.Server
+	challenge string
+	profile   googleProfile
+}
+
+func newFakeGoogle(t *testing.T, profile googleProfile) *fakeGoogle {
+	t.Helper()
+	f := &fakeGoogle{profile: profile}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != "code-ok" || base64.RawURLEncoding.EncodeToString(sum[:]) != f.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"access_token":"tok-1","token_type":"Bearer","expires_in":3600}`))
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok-1" {
+			http.Error(w, "sem token", http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(f.profile)
+	})
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+
+	t.Setenv("GOOGLE_AUTH_URL", f.URL+"/auth")
+	t.Setenv("GOOGLE_TOKEN_URL", f.URL+"/token")
+	t.Setenv("GOOGLE_USERINFO_URL", f.URL+"/userinfo")
+	t.Setenv("GOOGLE_CLIENT_ID", "client-1")
+	t.Setenv("GOOGLE_CLIENT_SECRET", "secret-1")
+	t.Setenv("GOOGLE_REDIRECT_URL", "http://klens.test/auth/google/callback")
+	return f
+}
+
+// withTestDB troca o banco global por um em memória durante o teste
+func withTestDB(t *testing.T, models ...any) {
+	t.Helper()
+	previous := db.DB
+	db.DB = dbtest.Open(t, models...)
+	t.Cleanup(func() { db.DB = previous })
+}
+
+// startLogin chama o /login, guarda o challenge PKCE no provedor e devolve o state e o cookie
+func startLogin(t *testing.T, f *fakeGoogle) (string, *http.Cookie) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	HandleGoogleLogin(rec, httptest.NewRequest(http.MethodGet, "/auth/google/login", nil))
+
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(location.String(), f.URL+"/auth") {
+		t.Fatalf("redirect inesperado: %q", rec.Header().Get("Location"))
+	}
+	f.challenge = location.Query().Get("code_challenge")
+	if location.Query().Get("code_challenge_method") != "S256" || f.challenge == "" {
+		t.Fatalf("login sem PKCE: %s", location)
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == oauthStateCookie {
+			return location.Query().Get("state"), c
+		}
+	}
+	t.Fatal("cookie de state não foi gravado")
+	return "", nil
+}
+
+func callback(state, code string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/auth/google/callback?"+url.Values{"state": {state}, "code": {code}}.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	HandleGoogleCallback(rec, req)
+	return rec
+}
+
+func sessionFrom(rec *httptest.ResponseRecorder) *http.Cookie {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == sessionCookie && c.Value != "" {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestGoogleCallbackCreatesUserAndSession(t *testing.T) {
+	withTestDB(t, &models.User{}, &models.Session{})
+	f := newFakeGoogle(t, googleProfile{Sub: "g-1", Email: "army@klens.test", EmailVerified: true, Name: "Army"})
+
+	state, cookie := startLogin(t, f)
+	rec := callback(state, "code-ok", cookie)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/studio.html" {
+		t.Fatalf("callback: %d %s", rec.Code, rec.Body.String())
+	}
+	session := sessionFrom(rec)
+	if session == nil {
+		t.Fatal("sessão não foi aberta")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	req.AddCookie(session)
+	user, err := CurrentUser(req)
+	if err != nil || user.GoogleID != "g-1" || user.Email != "army@klens.test" {
+		t.Fatalf("usuário da sessão: %+v, %v", user, err)
+	}
+}
+
+func TestGoogleCallbackUpsertsExistingUser(t *testing.T) {
+	cases := []struct {
+		name     string
+		existing models.User
+		profile  googleProfile
+	}{
+		{
+			name:     "por google_id",
+			existing: models.User{GoogleID: "g-2", Email: "antigo@klens.test", IsVIP: true},
+			profile:  googleProfile{Sub: "g-2", Email: "novo@klens.test", EmailVerified: true, Name: "Novo"},
+		},
+		{
+			name:     "por email",
+			existing: models.User{GoogleID: "legado", Email: "army@klens.test", IsVIP: true},
+			profile:  googleProfile{Sub: "g-3", Email: "army@klens.test", EmailVerified: true, Name: "Army"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			withTestDB(t, &models.User{}, &models.Session{})
+			if err := db.DB.Create(&tc.existing).Error; err != nil {
+				t.Fatal(err)
+			}
+			f := newFakeGoogle(t, tc.profile)
+
+			state, cookie := startLogin(t, f)
+			if rec := callback(state, "code-ok", cookie); rec.Code != http.StatusSeeOther {
+				t.Fatalf("callback: %d %s", rec.Code, rec.Body.String())
+			}
+
+			var users []models.User
+			db.DB.Find(&users)
+			if len(users) != 1 {
+				t.Fatalf("esperava atualizar o usuário existente, há %d", len(users))
+			}
+			got := users[0]
+			if got.ID != tc.existing.ID || got.GoogleID != tc.profile.Sub || got.Email != tc.profile.Email || got.Name != tc.profile.Name {
+				t.Fatalf("upsert: %+v", got)
+			}
+			if !got.IsVIP {
+				t.Fatal("o upsert não pode apagar o VIP")
+			}
+		})
+	}
+}
+
+func TestGoogleCallbackRejectsBadState(t *testing.T) {
+	withTestDB(t, &models.User{}, &models.Session{})
+	f := newFakeGoogle(t, googleProfile{Sub: "g-1", Email: "army@klens.test", EmailVerified: true})
+
+	state, cookie := startLogin(t, f)
+	tampered := *cookie
+	tampered.Value = strings.Replace(cookie.Value, state, state+"x", 1)
+	forged := &http.Cookie{Name: oauthStateCookie, Value: "forjado|verifier|9999999999.assinatura"}
+
+	cases := []struct {
+		name   string
+		state  string
+		cookie *http.Cookie
+	}{
+		{"state da query adulterado", state + "x", cookie},
+		{"cookie adulterado", state + "x", &tampered},
+		{"cookie forjado", "forjado", forged},
+		{"sem cookie", state, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := callback(tc.state, "code-ok", tc.cookie)
+			if rec.Code != http.StatusBadRequest || sessionFrom(rec) != nil {
+				t.Fatalf("callback deveria recusar: %d %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	var count int64
+	db.DB.Model(&models.User{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("nenhum usuário deveria ser criado, há %d", count)
+	}
+}
+
+func TestGoogleCallbackChecksPKCEVerifier(t *testing.T) {
+	withTestDB(t, &models.User{}, &models.Session{})
+	f := newFakeGoogle(t, googleProfile{Sub: "g-1", Email: "army@klens.test", EmailVerified: true})
+
+	state, cookie := startLogin(t, f)
+	// Outro login no meio do caminho: o verifier do cookie antigo não bate com o challenge novo
+	startLogin(t, f)
+	if rec := callback(state, "code-ok", cookie); rec.Code != http.StatusBadGateway || sessionFrom(rec) != nil {
+		t.Fatalf("troca com verifier errado deveria falhar: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestLogoutRequiresPost(t *testing.T) {
+	withTestDB(t, &models.User{}, &models.Session{})
+	db.DB.Create(&models.Session{TokenHash: hashToken("tok-sessao"), UserID: 1, ExpiresAt: time.Now().Add(time.Hour)})
+	session := &http.Cookie{Name: sessionCookie, Value: "tok-sessao"}
+
+	get := httptest.NewRequest(http.MethodGet, "/auth/logout", nil)
+	get.AddCookie(session)
+	rec := httptest.NewRecorder()
+	HandleLogout(rec, get)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET deveria ser recusado: %d", rec.Code)
+	}
+
+	post := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	post.AddCookie(session)
+	HandleLogout(httptest.NewRecorder(), post)
+	var count int64
+	db.DB.Model(&models.Session{}).Count(&count)
+	if count != 0 {
+		t.Fatal("POST deveria apagar a sessão")
+	}
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"k-lens/billing"
+	"k-lens/db"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+var billingService *billing.Service
+
+// StartBilling prepara o processamento de webhooks (BILLING_WEBHOOK_SECRET) e o
+// expirador de VIPs. A carência vem de BILLING_GRACE_PERIOD (padrão 72h).
+func StartBilling(ctx context.Context) {
+	if db.DB == nil {
+		return
+	}
+	secret := os.Getenv("BILLING_WEBHOOK_SECRET")
+	if secret == "" {
+		log.Println("⚠️ [Billing] BILLING_WEBHOOK_SECRET não definida: webhooks serão recusados")
+	}
+
+	billingService = billing.NewService(db.DB, billing.NewHMACVerifier(secret), envDuration("BILLING_GRACE_PERIOD", 72*time.Hour))
+	go billingService.ExpireLoop(ctx, time.Minute)
+}
+
+// BillingWebhook recebe os eventos do gateway: POST /webhooks/billing
+func BillingWebhook(w http.ResponseWriter, r *http.Request) {
+	if billingService == nil {
+		http.Error(w, "Billing indisponível", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 256<<10))
+	if err != nil {
+		http.Error(w, "Corpo inválido", http.StatusBadRequest)
+		return
+	}
+
+	ev, err := billingService.Handle(r.Header, body)
+	switch {
+	case errors.Is(err, billing.ErrDuplicate):
+		// Reenvio do gateway: já aplicado, confirma para ele parar de tentar
+		w.WriteHeader(http.StatusOK)
+		return
+	case errors.Is(err, billing.ErrUnknownUser):
+		// Assinatura sem usuário: recusar só faria o gateway reenviar para sempre
+		log.Printf("⚠️ [Billing] Evento %s da assinatura %s sem usuário no k-lens (%s), registrado sem aplicar",
+			ev.ID, ev.Data.SubscriptionID, ev.Data.CustomerEmail)
+		writeJSON(w, http.StatusOK, map[string]string{"received": ev.ID, "status": "unknown_user"})
+		return
+	case errors.Is(err, billing.ErrInvalid):
+		log.Printf("❌ [Billing] Webhook recusado: %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		// Falha nossa (banco): 500 faz o gateway reenviar depois
+		log.Printf("❌ [Billing] Erro ao processar webhook: %v", err)
+		http.Error(w, "Erro ao processar evento", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"received": ev.ID})
+}
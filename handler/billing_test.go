@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"bytes"
+	"k-lens/billing"
+	"k-lens/db"
+	"k-lens/models"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBillingWebhookAcknowledgesUnknownUser(t *testing.T) {
+	withTestDB(t, &models.User{}, &models.SubscriptionHistory{})
+	secret := []byte("whsec_teste")
+	previous := billingService
+	billingService = billing.NewService(db.DB, billing.NewHMACVerifier(string(secret)), time.Hour)
+	t.Cleanup(func() { billingService = previous })
+
+	body := []byte(`{"id":"evt_1","type":"subscription.created","created":` + strconv.FormatInt(time.Now().Unix(), 10) +
+		`,"data":{"subscription_id":"sub_sem_usuario","customer_email":"ninguem@klens.test"}}`)
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/billing", bytes.NewReader(body))
+		req.Header.Set(billing.SignatureHeader, billing.SignatureHeaderValue(secret, time.Now(), body))
+		rec := httptest.NewRecorder()
+		BillingWebhook(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("entrega %d: status %d (%s), o gateway ficaria reenviando", i+1, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestBillingWebhookStatus(t *testing.T) {
+	withTestDB(t, &models.User{}, &models.SubscriptionHistory{})
+	secret := []byte("whsec_teste")
+	previous := billingService
+	billingService = billing.NewService(db.DB, billing.NewHMACVerifier(string(secret)), time.Hour)
+	t.Cleanup(func() { billingService = previous })
+
+	event := func(id string) []byte {
+		return []byte(`{"id":"` + id + `","type":"subscription.renewed","created":` + strconv.FormatInt(time.Now().Unix(), 10) +
+			`,"data":{"subscription_id":"sub_1"}}`)
+	}
+	post := func(body []byte, signature string) int {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/billing", bytes.NewReader(body))
+		req.Header.Set(billing.SignatureHeader, signature)
+		rec := httptest.NewRecorder()
+		BillingWebhook(rec, req)
+		return rec.Code
+	}
+
+	if code := post(event("evt_1"), billing.SignatureHeaderValue([]byte("outro"), time.Now(), event("evt_1"))); code != http.StatusBadRequest {
+		t.Fatalf("assinatura errada: status %d, esperava 400", code)
+	}
+	malformed := []byte(`{"id":"evt_2"}`)
+	if code := post(malformed, billing.SignatureHeaderValue(secret, time.Now(), malformed)); code != http.StatusBadRequest {
+		t.Fatalf("payload malformado: status %d, esperava 400", code)
+	}
+
+	// Erro do nosso lado: 500 para o gateway reenviar
+	db.DB.Migrator().DropTable(&models.SubscriptionHistory{})
+	body := event("evt_3")
+	if code := post(body, billing.SignatureHeaderValue(secret, time.Now(), body)); code != http.StatusInternalServerError {
+		t.Fatalf("falha do banco: status %d, esperava 500", code)
+	}
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"k-lens/db"
+	"k-lens/models"
+	"k-lens/subtitle"
+	"k-lens/translate"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ExportCaptions devolve as legendas de uma live em srt, vtt, ttml ou json.
+//
+//	GET /api/lives/{id}/captions/{format}?from=<ms>&to=<ms>&merge=1&max_duration=<ms>
+//
+// from/to filtram pelo offset desde o início da live; merge junta legendas curtas vizinhas;
+// max_duration limita quanto tempo uma legenda fica na tela sem a próxima chegar.
+func ExportCaptions(w http.ResponseWriter, r *http.Request) {
+	if db.DB == nil {
+		http.Error(w, "Banco de dados indisponível", http.StatusServiceUnavailable)
+		return
+	}
+
+	vars := mux.Vars(r)
+	liveID, err := strconv.ParseUint(vars["id"], 10, 32)
+	if err != nil {
+		http.Error(w, "ID de live inválido", http.StatusBadRequest)
+		return
+	}
+
+	format := vars["format"]
+	contentType, ok := captionContentTypes[format]
+	if !ok {
+		http.Error(w, "Formato não suportado (use srt, vtt, ttml ou json)", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	maxDuration := subtitle.DefaultMaxDuration
+	if v := q.Get("max_duration"); v != "" {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ms <= 0 {
+			http.Error(w, "max_duration inválido", http.StatusBadRequest)
+			return
+		}
+		maxDuration = time.Duration(ms) * time.Millisecond
+	}
+
+	from, errFrom := parseOptionalMs(q.Get("from"))
+	to, errTo := parseOptionalMs(q.Get("to"))
+	if errFrom != nil || errTo != nil || (to > 0 && to <= from) {
+		http.Error(w, "Intervalo from/to inválido", http.StatusBadRequest)
+		return
+	}
+
+	var live models.LiveArchive
+	if err := db.DB.First(&live, liveID).Error; err != nil {
+		http.Error(w, "Live não encontrada", http.StatusNotFound)
+		return
+	}
+
+	// Transcrição completa é conteúdo VIP (ou da equipe da live)
+	user, role, err := liveAccess(r, live.ID)
+	if err != nil {
+		status, msg := accessStatus(err)
+		http.Error(w, msg, status)
+		return
+	}
+	if role == models.RoleViewer && (user == nil || !user.IsVIP) {
+		http.Error(w, "Exportação de legendas é exclusiva para VIPs", http.StatusForbidden)
+		return
+	}
+
+	// Um idioma por arquivo: ?lang=en (padrão: idioma principal da live)
+	lang := pickLanguage(live.ID, "")
+	if v := q.Get("lang"); v != "" {
+		requested, ok := translate.NormalizeLanguage(v)
+		if !ok || pickLanguage(live.ID, requested) != requested {
+			http.Error(w, "Idioma não configurado nesta live", http.StatusBadRequest)
+			return
+		}
+		lang = requested
+	}
+	// Busca um pouco antes de "from" para recuperar a legenda que já estava na tela
+	query := db.DB.Where("live_archive_id = ? AND language = ?", liveID, lang)
+	if from > 0 {
+		query = query.Where("timestamp >= ?", (from - maxDuration).Milliseconds())
+	}
+	if to > 0 {
+		query = query.Where("timestamp < ?", to.Milliseconds())
+	}
+	var captions []models.CaptionLog
+	if err := query.Order("timestamp").Find(&captions).Error; err != nil {
+		http.Error(w, "Erro ao buscar legendas", http.StatusInternalServerError)
+		return
+	}
+	// Correções dos moderadores valem sobre o texto original
+	var revisions []models.CaptionRevision
+	if err := db.DB.Where("live_archive_id = ? AND language = ?", liveID, lang).Find(&revisions).Error; err != nil {
+		http.Error(w, "Erro ao buscar correções", http.StatusInternalServerError)
+		return
+	}
+	captions = subtitle.ApplyRevisions(captions, revisions)
+
+	cues := subtitle.FromCaptions(captions, maxDuration)
+	if from > 0 || to > 0 {
+		end := to
+		if end == 0 {
+			end = time.Duration(math.MaxInt64)
+		}
+		cues = subtitle.Retime(cues, from, end)
+		// Mantém os tempos relativos ao início da live
+		for i := range cues {
+			cues[i].Start += from
+			cues[i].End += from
+		}
+	}
+	if q.Get("merge") == "1" || q.Get("merge") == "true" {
+		cues = subtitle.Merge(cues, 1500*time.Millisecond, 300*time.Millisecond, 2*maxDuration)
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename=\"live_"+vars["id"]+"."+format+"\"")
+	switch format {
+	case "srt":
+		err = subtitle.WriteSRT(w, cues)
+	case "vtt":
+		err = subtitle.WriteVTT(w, cues)
+	case "ttml":
+		err = subtitle.WriteTTML(w, cues, lang)
+	case "json":
+		err = subtitle.WriteJSON(w, cues)
+	}
+	if err != nil {
+		log.Printf("⚠️ [Legendas] Erro ao exportar live %s: %v", vars["id"], err)
+	}
+}
+
+var captionContentTypes = map[string]string{
+	"srt":  "application/x-subrip; charset=utf-8",
+	"vtt":  "text/vtt; charset=utf-8",
+	"ttml": "application/ttml+xml; charset=utf-8",
+	"json": "application/json",
+}
+
+func parseOptionalMs(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms < 0 {
+		return 0, strconv.ErrSyntax
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
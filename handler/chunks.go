@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"k-lens/audio"
+)
+
+// Limites de um trecho comprimido antes de ir ao tradutor
+const (
+	maxChunkSpanMs = 5000
+	maxChunkBytes  = 512 << 10
+)
+
+// AudioChunk é um trecho de áudio comprimido pronto para uma tradução
+type AudioChunk struct {
+	Audio    []byte
+	MIMEType string
+	StartMs  int64 // Milissegundos desde o início da live
+	EndMs    int64
+}
+
+// ChunkBuffer junta os chunks comprimidos (webm/ogg/flac do MediaRecorder) de uma conexão.
+// O cabeçalho do container só vem no primeiro chunk: ele é guardado e colocado na
+// frente de cada trecho enviado. O trecho sai ao passar de maxChunkSpanMs ou maxChunkBytes.
+type ChunkBuffer struct {
+	format audio.Format
+
+	encoding string // Codificação do cabeçalho guardado (pode vir do sniff)
+	header   []byte
+	buf      []byte
+	startMs  int64
+	lastMs   int64 // Chegada do chunk anterior: é onde o próximo começa
+}
+
+func NewChunkBuffer(format audio.Format) *ChunkBuffer {
+	return &ChunkBuffer{format: format, encoding: format.Encoding}
+}
+
+// Push recebe um chunk que chegou em offsetMs (fim do chunk) e devolve o trecho
+// fechado com ele, se algum limite foi atingido.
+func (b *ChunkBuffer) Push(chunk []byte, offsetMs int64) (AudioChunk, bool) {
+	// WAV é autocontido: cada chunk vai sozinho
+	if audio.Sniff(chunk) == audio.EncodingWAV {
+		start := b.lastMs
+		if start == 0 || start > offsetMs {
+			start = offsetMs
+		}
+		b.lastMs = offsetMs
+		blob, mimeType := b.format.Encode(chunk)
+		return AudioChunk{Audio: blob, MIMEType: mimeType, StartMs: start, EndMs: offsetMs}, true
+	}
+
+	// Chunk com cabeçalho novo (início da gravação ou gravador reiniciado)
+	if sniffed := audio.StreamStart(chunk); sniffed != "" {
+		var done AudioChunk
+		flushed := false
+		if len(b.buf) > 0 {
+			done, flushed = b.Flush()
+		}
+		b.encoding = sniffed
+		b.header, chunk = audio.SplitHeader(sniffed, chunk)
+		b.header = append([]byte(nil), b.header...)
+		if len(chunk) > 0 {
+			b.add(chunk, offsetMs)
+		} else {
+			b.lastMs = offsetMs
+		}
+		return done, flushed
+	}
+
+	b.add(chunk, offsetMs)
+	if b.lastMs-b.startMs >= maxChunkSpanMs || len(b.buf) >= maxChunkBytes {
+		return b.Flush()
+	}
+	return AudioChunk{}, false
+}
+
+func (b *ChunkBuffer) add(chunk []byte, offsetMs int64) {
+	if len(b.buf) == 0 {
+		b.startMs = b.lastMs
+		if b.startMs == 0 || b.startMs > offsetMs {
+			b.startMs = offsetMs
+		}
+	}
+	b.buf = append(b.buf, chunk...)
+	b.lastMs = offsetMs
+}
+
+// Streaming indica que um cabeçalho de container já chegou: os próximos chunks
+// são continuação dele mesmo que a conexão tenha negociado PCM
+func (b *ChunkBuffer) Streaming() bool {
+	return len(b.header) > 0
+}
+
+// Flush fecha o que estiver acumulado (fim da conexão ou troca de formato)
+func (b *ChunkBuffer) Flush() (AudioChunk, bool) {
+	if len(b.buf) == 0 {
+		return AudioChunk{}, false
+	}
+	blob := make([]byte, 0, len(b.header)+len(b.buf))
+	blob = append(append(blob, b.header...), b.buf...)
+	b.buf = b.buf[:0]
+	return AudioChunk{
+		Audio:    blob,
+		MIMEType: audio.Format{Encoding: b.encoding}.MIMEType(),
+		StartMs:  b.startMs,
+		EndMs:    b.lastMs,
+	}, true
+}
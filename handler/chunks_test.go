@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"bytes"
+	"k-lens/audio"
+	"testing"
+)
+
+func TestChunkBufferPrependsWebMHeader(t *testing.T) {
+	header := append([]byte{0x1A, 0x45, 0xDF, 0xA3}, []byte("ebml+segment+tracks")...)
+	cluster1 := append([]byte{0x1F, 0x43, 0xB6, 0x75}, bytes.Repeat([]byte{1}, 100)...)
+	cluster2 := append([]byte{0x1F, 0x43, 0xB6, 0x75}, bytes.Repeat([]byte{2}, 100)...)
+
+	b := NewChunkBuffer(audio.Format{Encoding: audio.EncodingWebM})
+	if _, ok := b.Push(append(append([]byte{}, header...), cluster1...), 1000); ok {
+		t.Fatal("primeiro chunk não deveria fechar trecho")
+	}
+	if _, ok := b.Push(cluster2, 2000); ok {
+		t.Fatal("trecho fechou antes do limite")
+	}
+	c, ok := b.Push(cluster2, 1000+maxChunkSpanMs)
+	if !ok {
+		t.Fatal("trecho deveria fechar pelo tempo")
+	}
+	if !bytes.HasPrefix(c.Audio, header) || bytes.Count(c.Audio, header) != 1 {
+		t.Fatal("trecho sem o cabeçalho do container na frente")
+	}
+	if c.MIMEType != "audio/webm" || c.StartMs != 1000 || c.EndMs != 1000+maxChunkSpanMs {
+		t.Fatalf("trecho inesperado: %s %d-%d", c.MIMEType, c.StartMs, c.EndMs)
+	}
+
+	// O trecho seguinte também decodifica sozinho e começa onde o anterior terminou
+	b.Push(cluster1, 7000)
+	c, ok = b.Flush()
+	if !ok || !bytes.HasPrefix(c.Audio, header) || c.StartMs != 1000+maxChunkSpanMs || c.EndMs != 7000 {
+		t.Fatalf("flush inesperado: %v %d-%d", ok, c.StartMs, c.EndMs)
+	}
+}
+
+func TestSplitHeaderOgg(t *testing.T) {
+	page := func(flags byte, granule byte, body string) []byte {
+		p := []byte("OggS")
+		p = append(p, 0, flags, granule, 0, 0, 0, 0, 0, 0, 0)
+		p = append(p, make([]byte, 12)...) // serial, sequência, CRC
+		p = append(p, 1, byte(len(body)))
+		return append(p, body...)
+	}
+	head := append(page(0x02, 0, "OpusHead"), page(0, 0, "OpusTags")...)
+	data := append(append([]byte{}, head...), page(0, 9, "audio")...)
+
+	if audio.StreamStart(page(0, 9, "audio")) != "" {
+		t.Fatal("página de continuação não abre fluxo")
+	}
+	header, rest := audio.SplitHeader(audio.StreamStart(data), data)
+	if !bytes.Equal(header, head) || !bytes.Equal(rest, page(0, 9, "audio")) {
+		t.Fatalf("cabeçalho ogg com %d bytes, resto %d", len(header), len(rest))
+	}
+}
@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"errors"
+	"k-lens/db"
+	"k-lens/models"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// maxClipTitle limita o nome exibido do clipe
+const maxClipTitle = 120
+
+// requireClipViewer libera o catálogo para VIPs e para a equipe da live (como os downloads)
+func requireClipViewer(w http.ResponseWriter, r *http.Request, liveID uint) bool {
+	user, role, err := liveAccess(r, liveID)
+	if err != nil {
+		status, msg := accessStatus(err)
+		http.Error(w, msg, status)
+		return false
+	}
+	if role == models.RoleViewer && (user == nil || !user.IsVIP) {
+		http.Error(w, "Clipes são exclusivos para VIPs", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// ListClips lista os clipes da live: GET /api/lives/{id}/clips
+// Filtros opcionais: status, label, ratio, from/to (ms desde o início da live), limit (padrão 100)
+func ListClips(w http.ResponseWriter, r *http.Request) {
+	if db.DB == nil {
+		http.Error(w, "Banco de dados indisponível", http.StatusServiceUnavailable)
+		return
+	}
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !requireClipViewer(w, r, id) {
+		return
+	}
+
+	q := r.URL.Query()
+	limit := 100
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 500 {
+			http.Error(w, "limit deve ficar entre 1 e 500", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	query := db.DB.Where("live_archive_id = ?", id).Order("start_ms, id").Limit(limit)
+	if v := q.Get("status"); v != "" {
+		query = query.Where("status = ?", v)
+	}
+	if v := q.Get("label"); v != "" {
+		query = query.Where("label = ?", v)
+	}
+	if v := q.Get("ratio"); v != "" {
+		query = query.Where("aspect_ratio = ?", v)
+	}
+	from, errFrom := parseOptionalMs(q.Get("from"))
+	to, errTo := parseOptionalMs(q.Get("to"))
+	if errFrom != nil || errTo != nil || (to > 0 && to <= from) {
+		http.Error(w, "Intervalo from/to inválido", http.StatusBadRequest)
+		return
+	}
+	if from > 0 {
+		query = query.Where("end_ms > ?", from.Milliseconds())
+	}
+	if to > 0 {
+		query = query.Where("start_ms < ?", to.Milliseconds())
+	}
+
+	var clips []models.Clip
+	if err := query.Find(&clips).Error; err != nil {
+		http.Error(w, "Erro ao listar clipes", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, clips)
+}
+
+// GetClip busca um clipe: GET /api/lives/{id}/clips/{clip}
+func GetClip(w http.ResponseWriter, r *http.Request) {
+	clip, ok := loadClip(w, r)
+	if !ok {
+		return
+	}
+	if !requireClipViewer(w, r, clip.LiveArchiveID) {
+		return
+	}
+	writeJSON(w, http.StatusOK, clip)
+}
+
+// RenameClip troca o nome exibido: PATCH /api/lives/{id}/clips/{clip}
+// Body: {"title": "Jimin rindo"}. O arquivo no storage mantém o nome único.
+func RenameClip(w http.ResponseWriter, r *http.Request) {
+	clip, ok := loadClip(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := requireLiveAction(w, r, clip.LiveArchiveID, actionClip); !ok {
+		return
+	}
+
+	var req struct {
+		Title string `json:"title"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	title := strings.TrimSpace(req.Title)
+	if title == "" || len([]rune(title)) > maxClipTitle {
+		http.Error(w, "title deve ter entre 1 e 120 caracteres", http.StatusBadRequest)
+		return
+	}
+	if err := db.DB.Model(clip).Update("title", title).Error; err != nil {
+		http.Error(w, "Erro ao renomear clipe", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, clip)
+}
+
+// DeleteClip remove o clipe do catálogo e os arquivos dele: DELETE /api/lives/{id}/clips/{clip}
+func DeleteClip(w http.ResponseWriter, r *http.Request) {
+	clip, ok := loadClip(w, r)
+	if !ok {
+		return
+	}
+	if _, ok := requireLiveAction(w, r, clip.LiveArchiveID, actionClip); !ok {
+		return
+	}
+	if clip.Status == models.ClipProcessing {
+		http.Error(w, "Clipe ainda em processamento", http.StatusConflict)
+		return
+	}
+
+	if err := videoCutter.RemoveClipFiles(clip.FileName); err != nil {
+		log.Printf("❌ [Catálogo] Erro ao apagar %s: %v", clip.FileName, err)
+		http.Error(w, "Erro ao apagar arquivos do clipe", http.StatusInternalServerError)
+		return
+	}
+	if err := db.DB.Delete(clip).Error; err != nil {
+		http.Error(w, "Erro ao remover clipe", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("🗑️ [Catálogo] Clipe %d (%s) removido da live %d", clip.ID, clip.FileName, clip.LiveArchiveID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ServeRecording entrega o arquivo de um clipe: GET /recordings/{file}
+// Só arquivos do catálogo, com o mesmo acesso dele (VIP ou equipe da live).
+// As legendas sidecar (.srt/.vtt) seguem o acesso do mp4 de mesmo nome.
+func ServeRecording(w http.ResponseWriter, r *http.Request) {
+	if db.DB == nil {
+		http.Error(w, "Banco de dados indisponível", http.StatusServiceUnavailable)
+		return
+	}
+	name := mux.Vars(r)["file"]
+	if name != filepath.Base(name) {
+		http.NotFound(w, r)
+		return
+	}
+	fileName := name
+	switch ext := filepath.Ext(name); ext {
+	case ".mp4":
+	case ".srt", ".vtt":
+		fileName = strings.TrimSuffix(name, ext) + ".mp4"
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	var clip models.Clip
+	if err := db.DB.Where("file_name = ?", fileName).First(&clip).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, "Erro ao buscar clipe", http.StatusInternalServerError)
+		return
+	}
+	if !requireClipViewer(w, r, clip.LiveArchiveID) {
+		return
+	}
+
+	f, err := os.Open(videoCutter.ClipPath(name))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeContent(w, r, name, info.ModTime(), f)
+}
+
+// loadClip busca o clipe da live da URL
+func loadClip(w http.ResponseWriter, r *http.Request) (*models.Clip, bool) {
+	if db.DB == nil {
+		http.Error(w, "Banco de dados indisponível", http.StatusServiceUnavailable)
+		return nil, false
+	}
+	liveID, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	clipID, err := strconv.ParseUint(mux.Vars(r)["clip"], 10, 32)
+	if err != nil || clipID == 0 {
+		http.Error(w, "ID de clipe inválido", http.StatusBadRequest)
+		return nil, false
+	}
+	var clip models.Clip
+	if err := db.DB.Where("live_archive_id = ?", liveID).First(&clip, clipID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Clipe não encontrado", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Erro ao buscar clipe", http.StatusInternalServerError)
+		return nil, false
+	}
+	return &clip, true
+}
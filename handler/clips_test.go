@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"k-lens/db"
+	"k-lens/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// loginAs cria o usuário com uma sessão e devolve o cookie dela
+func loginAs(t *testing.T, user *models.User) *http.Cookie {
+	t.Helper()
+	if err := db.DB.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	token := randomToken(32)
+	db.DB.Create(&models.Session{TokenHash: hashToken(token), UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)})
+	return &http.Cookie{Name: sessionCookie, Value: token}
+}
+
+// inviteToken emite um convite registrado no banco para a live
+func inviteToken(t *testing.T, liveID uint, role string) string {
+	t.Helper()
+	claims := AccessClaims{ID: randomToken(8), Subject: "invite", Role: role, LiveID: liveID, ExpiresAt: time.Now().Add(time.Hour).Unix()}
+	db.DB.Create(&models.AccessInvite{TokenID: claims.ID, LiveArchiveID: liveID, Role: role, ExpiresAt: time.Unix(claims.ExpiresAt, 0)})
+	return mintToken(claims)
+}
+
+func TestServeRecordingChecksClipAccess(t *testing.T) {
+	withTestDB(t, &models.User{}, &models.Session{}, &models.LiveMember{}, &models.AccessInvite{}, &models.Clip{})
+	storage := t.TempDir()
+	previous := videoCutter.StoragePath
+	videoCutter.StoragePath = storage
+	t.Cleanup(func() { videoCutter.StoragePath = previous })
+
+	const clipFile = "KLENS_1_destaque_9x16_20260101-120000_abcd1234.mp4"
+	for name, content := range map[string]string{
+		clipFile: "mp4",
+		"KLENS_1_destaque_9x16_20260101-120000_abcd1234.srt": "srt",
+		"fora_do_catalogo.mp4":                               "solto",
+	} {
+		os.WriteFile(filepath.Join(storage, name), []byte(content), 0644)
+	}
+	db.DB.Create(&models.Clip{LiveArchiveID: 1, ClipJobID: 1, FileName: clipFile, Status: models.ClipReady})
+
+	router := mux.NewRouter()
+	router.Use(SecurityMiddleware)
+	router.HandleFunc("/recordings/{file}", ServeRecording).Methods("GET", "HEAD")
+
+	vip := loginAs(t, &models.User{GoogleID: "g-vip", Email: "vip@klens.test", IsVIP: true})
+	free := loginAs(t, &models.User{GoogleID: "g-free", Email: "free@klens.test"})
+
+	cases := []struct {
+		name   string
+		file   string
+		cookie *http.Cookie
+		token  string
+		status int
+		body   string
+	}{
+		{"VIP baixa o mp4", clipFile, vip, "", http.StatusOK, "mp4"},
+		{"VIP baixa a legenda sidecar", "KLENS_1_destaque_9x16_20260101-120000_abcd1234.srt", vip, "", http.StatusOK, "srt"},
+		{"viewer sem VIP", clipFile, free, "", http.StatusForbidden, ""},
+		{"convite de moderador da live", clipFile, nil, inviteToken(t, 1, models.RoleModerator), http.StatusOK, "mp4"},
+		{"convite de viewer da live", clipFile, nil, inviteToken(t, 1, models.RoleViewer), http.StatusForbidden, ""},
+		{"convite de outra live", clipFile, nil, inviteToken(t, 2, models.RoleModerator), http.StatusForbidden, ""},
+		{"arquivo fora do catálogo", "fora_do_catalogo.mp4", vip, "", http.StatusNotFound, ""},
+		{"sem sessão nem convite", clipFile, nil, "", http.StatusUnauthorized, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/recordings/"+tc.file, nil)
+			if tc.cookie != nil {
+				req.AddCookie(tc.cookie)
+			}
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Fatalf("status %d, esperava %d: %s", rec.Code, tc.status, rec.Body.String())
+			}
+			if tc.body != "" && rec.Body.String() != tc.body {
+				t.Fatalf("conteúdo %q, esperava %q", rec.Body.String(), tc.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"k-lens/translate"
+	"sync"
+)
+
+// liveContext guarda, por live, as últimas falas finais (coreano + legenda principal)
+// que acompanham cada novo trecho de áudio enviado ao tradutor.
+// CONTEXT_TURNS (padrão 8) limita a quantidade; CONTEXT_TOKEN_BUDGET (padrão 600) o tamanho.
+type liveContext struct {
+	once     sync.Once
+	maxTurns int
+	budget   int
+
+	mu     sync.Mutex
+	byLive map[string][]translate.ContextTurn
+}
+
+var contexts = &liveContext{byLive: make(map[string][]translate.ContextTurn)}
+
+func (c *liveContext) load() {
+	c.once.Do(func() {
+		c.maxTurns = envInt("CONTEXT_TURNS", 8)
+		c.budget = envInt("CONTEXT_TOKEN_BUDGET", 600)
+	})
+}
+
+// Window devolve o contexto atual da live, já dentro do orçamento
+func (c *liveContext) Window(liveID string) []translate.ContextTurn {
+	c.load()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	turns := c.byLive[liveID]
+	out := make([]translate.ContextTurn, len(turns))
+	copy(out, turns)
+	return out
+}
+
+// Add registra uma fala final e corta o excesso
+func (c *liveContext) Add(liveID string, turn translate.ContextTurn) {
+	c.load()
+	if c.maxTurns == 0 || (turn.Transcript == "" && turn.Caption == "") {
+		return
+	}
+	c.mu.Lock()
+	c.byLive[liveID] = translate.TrimContext(append(c.byLive[liveID], turn), c.maxTurns, c.budget)
+	c.mu.Unlock()
+}
+
+// forget zera o contexto quando a live termina
+func (c *liveContext) forget(liveID string) {
+	c.mu.Lock()
+	delete(c.byLive, liveID)
+	c.mu.Unlock()
+}
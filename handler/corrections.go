@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"k-lens/db"
+	"k-lens/hub"
+	"k-lens/models"
+	"k-lens/translate"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+var (
+	errCaptionNotFound = errors.New("legenda não encontrada")
+	errBadCorrection   = errors.New("correção inválida")
+)
+
+// captionCorrection é uma edição (Text != nil) ou retirada (Text == nil) de legenda
+type captionCorrection struct {
+	LiveID    uint
+	CaptionID string
+	Language  string  // Vazio: na retirada, todos os idiomas; na edição, o principal da live
+	Text      *string // nil = retirar
+	Speaker   *string // nil = mantém o locutor atual
+	AuthorID  uint
+}
+
+// applyCorrection grava uma revisão por linha afetada e avisa os clientes
+func applyCorrection(h *hub.Hub, c captionCorrection) ([]models.CaptionRevision, error) {
+	if c.CaptionID == "" {
+		return nil, fmt.Errorf("%w: caption_id é obrigatório", errBadCorrection)
+	}
+	if c.Text != nil {
+		text := strings.TrimSpace(*c.Text)
+		if text == "" {
+			return nil, fmt.Errorf("%w: texto vazio (para retirar a legenda use caption_delete)", errBadCorrection)
+		}
+		c.Text = &text
+		if c.Language == "" {
+			c.Language = lives.Languages(c.LiveID)[0]
+		}
+	}
+	if c.Language != "" {
+		lang, ok := translate.NormalizeLanguage(c.Language)
+		if !ok {
+			return nil, fmt.Errorf("%w: idioma não suportado: %s", errBadCorrection, c.Language)
+		}
+		c.Language = lang
+	}
+	if c.Speaker != nil {
+		speaker := resolveSpeaker(lives.Roster(c.LiveID), *c.Speaker)
+		if *c.Speaker != "" && speaker == "" {
+			return nil, fmt.Errorf("%w: membro fora do elenco: %s", errBadCorrection, *c.Speaker)
+		}
+		c.Speaker = &speaker
+	}
+
+	// Ainda na fila de revisão: a correção decide o que vai ao ar (sem revisão no banco ainda)
+	if reviews.correct(h, c) {
+		return []models.CaptionRevision{}, nil
+	}
+	if db.DB == nil {
+		return nil, fmt.Errorf("banco de dados indisponível")
+	}
+
+	var revisions []models.CaptionRevision
+	vipOnly := map[uint]bool{} // Linhas que nunca foram ao feed gratuito
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("live_archive_id = ? AND caption_id = ?", c.LiveID, c.CaptionID)
+		if c.Language != "" {
+			query = query.Where("language = ?", c.Language)
+		}
+		var rows []models.CaptionLog
+		if err := query.Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return errCaptionNotFound
+		}
+
+		for _, row := range rows {
+			vipOnly[row.ID] = row.IsVipOnly
+			// Parte do estado atual (última revisão) para manter o que não foi editado
+			var last models.CaptionRevision
+			err := tx.Where("caption_log_id = ?", row.ID).Order("revision DESC").First(&last).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			current := row
+			if last.ID != 0 {
+				if last.Deleted && c.Text == nil {
+					continue // Já retirada
+				}
+				current.Text, current.Speaker = last.Text, last.Speaker
+			}
+
+			rev := models.CaptionRevision{
+				CaptionLogID:  row.ID,
+				Revision:      last.Revision + 1,
+				CaptionID:     row.CaptionID,
+				LiveArchiveID: row.LiveArchiveID,
+				Language:      row.Language,
+				Text:          current.Text,
+				Speaker:       current.Speaker,
+				Deleted:       c.Text == nil,
+				AuthorID:      c.AuthorID,
+			}
+			if c.Text != nil {
+				rev.Text = *c.Text
+			}
+			if c.Speaker != nil {
+				rev.Speaker = *c.Speaker
+			}
+			if err := tx.Create(&rev).Error; err != nil {
+				return err
+			}
+			revisions = append(revisions, rev)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	liveIDStr := strconv.Itoa(int(c.LiveID))
+	for _, rev := range revisions {
+		msg := hub.Message{Type: "caption_update", Payload: models.WithSpeaker(rev.Speaker, rev.Text),
+			LiveID: liveIDStr, Language: rev.Language, CaptionID: rev.CaptionID, Speaker: rev.Speaker}
+		if rev.Deleted {
+			msg = hub.Message{Type: "caption_delete", LiveID: liveIDStr, Language: rev.Language, CaptionID: rev.CaptionID}
+		}
+		tiers.publishCorrection(h, msg, vipOnly[rev.CaptionLogID])
+	}
+	action := "corrigida"
+	if c.Text == nil {
+		action = "retirada"
+	}
+	log.Printf("✏️ [Legendas] Legenda %s %s (%d linhas) na live %d por user %d", c.CaptionID, action, len(revisions), c.LiveID, c.AuthorID)
+	return revisions, nil
+}
+
+// EditCaption corrige uma legenda: PUT /api/lives/{id}/captions/{caption}
+// Body: {"language": "pt-BR", "text": "...", "speaker": "Jimin"} (speaker opcional)
+func EditCaption(h *hub.Hub, w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	user, ok := requireLiveAction(w, r, id, actionEdit)
+	if !ok {
+		return
+	}
+	if user == nil {
+		http.Error(w, "Correções exigem login", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		Language string  `json:"language"`
+		Text     string  `json:"text"`
+		Speaker  *string `json:"speaker"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	revisions, err := applyCorrection(h, captionCorrection{
+		LiveID: id, CaptionID: mux.Vars(r)["caption"], Language: req.Language,
+		Text: &req.Text, Speaker: req.Speaker, AuthorID: user.ID,
+	})
+	writeCorrection(w, revisions, err)
+}
+
+// DeleteCaption retira uma legenda: DELETE /api/lives/{id}/captions/{caption}?language=en
+// Sem language, retira a fala em todos os idiomas.
+func DeleteCaption(h *hub.Hub, w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	user, ok := requireLiveAction(w, r, id, actionEdit)
+	if !ok {
+		return
+	}
+	if user == nil {
+		http.Error(w, "Correções exigem login", http.StatusForbidden)
+		return
+	}
+
+	revisions, err := applyCorrection(h, captionCorrection{
+		LiveID: id, CaptionID: mux.Vars(r)["caption"], Language: r.URL.Query().Get("language"), AuthorID: user.ID,
+	})
+	writeCorrection(w, revisions, err)
+}
+
+// ListCaptionRevisions mostra o histórico: GET /api/lives/{id}/captions/{caption}/revisions
+func ListCaptionRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireLiveAction(w, r, id, actionEdit); !ok {
+		return
+	}
+
+	var revisions []models.CaptionRevision
+	err = db.DB.Where("live_archive_id = ? AND caption_id = ?", id, mux.Vars(r)["caption"]).
+		Order("language, revision").Find(&revisions).Error
+	if err != nil {
+		http.Error(w, "Erro ao buscar revisões", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, revisions)
+}
+
+func writeCorrection(w http.ResponseWriter, revisions []models.CaptionRevision, err error) {
+	switch {
+	case errors.Is(err, errCaptionNotFound):
+		http.Error(w, "Legenda não encontrada", http.StatusNotFound)
+	case errors.Is(err, errBadCorrection):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err != nil:
+		log.Printf("❌ [Legendas] Erro ao gravar correção: %v", err)
+		http.Error(w, "Erro ao gravar correção", http.StatusInternalServerError)
+	default:
+		writeJSON(w, http.StatusOK, revisions)
+	}
+}
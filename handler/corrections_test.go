@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"k-lens/db"
+	"k-lens/hub"
+	"k-lens/models"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCorrectionOfVipOnlyCaptionSkipsFreeFeed(t *testing.T) {
+	withTestDB(t, &models.CaptionLog{}, &models.CaptionRevision{})
+	live := models.LiveArchive{ID: 9201, Languages: "pt-BR"}
+	h, vip := testRoom(t, live, "pt-BR")
+	free := hub.NewClient(strconv.Itoa(int(live.ID)))
+	free.Language = "pt-BR"
+	h.Register <- free
+
+	tiers.load()
+	delay := tiers.delay
+	tiers.delay = 10 * time.Millisecond
+	t.Cleanup(func() { tiers.delay = delay })
+
+	db.DB.Create(&models.CaptionLog{CaptionID: "c-vip", LiveArchiveID: live.ID, Text: "só VIP", Language: "pt-BR", IsVipOnly: true})
+	db.DB.Create(&models.CaptionLog{CaptionID: "c-free", LiveArchiveID: live.ID, Text: "para todos", Language: "pt-BR"})
+
+	for _, id := range []string{"c-vip", "c-free"} {
+		text := "corrigida " + id
+		if _, err := applyCorrection(h, captionCorrection{LiveID: live.ID, CaptionID: id, Text: &text, AuthorID: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, id := range []string{"c-vip", "c-free"} {
+		if msg := receive(t, vip); msg.Type != "caption_update" || msg.CaptionID != id {
+			t.Fatalf("VIP deveria receber a correção de %s: %+v", id, msg)
+		}
+	}
+	if msg := receive(t, free); msg.CaptionID != "c-free" {
+		t.Fatalf("feed gratuito recebeu a correção de uma legenda só para VIPs: %+v", msg)
+	}
+	select {
+	case msg := <-free.Send:
+		t.Fatalf("mensagem extra no feed gratuito: %+v", msg)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,297 @@
+package handler
+
+import (
+	"errors"
+	"k-lens/db"
+	"k-lens/models"
+	"k-lens/translate"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// glossaryTTL limita quanto tempo uma edição feita por outra instância demora a valer
+const glossaryTTL = time.Minute
+
+type cachedGlossary struct {
+	glossary *translate.Glossary
+	loadedAt time.Time
+}
+
+// glossaryCache guarda o glossário compilado por artista (IdolName da live)
+type glossaryCache struct {
+	mu       sync.Mutex
+	byArtist map[string]cachedGlossary
+}
+
+var glossaries = &glossaryCache{byArtist: make(map[string]cachedGlossary)}
+
+// For devolve os termos do artista mais os globais (Artist vazio)
+func (c *glossaryCache) For(artist string) *translate.Glossary {
+	key := strings.ToLower(strings.TrimSpace(artist))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cached, ok := c.byArtist[key]; ok && time.Since(cached.loadedAt) < glossaryTTL {
+		return cached.glossary
+	}
+	if db.DB == nil {
+		return translate.NewGlossary(nil)
+	}
+
+	var terms []models.GlossaryTerm
+	err := db.DB.Where("artist = '' OR LOWER(artist) = ?", key).
+		Order("artist DESC, id").Find(&terms).Error
+	if err != nil {
+		log.Printf("⚠️ [Glossário] Erro ao carregar termos de %q: %v", artist, err)
+		if cached, ok := c.byArtist[key]; ok {
+			return cached.glossary
+		}
+		return translate.NewGlossary(nil)
+	}
+
+	entries := make([]translate.GlossaryEntry, 0, len(terms))
+	for _, t := range terms {
+		entries = append(entries, translate.GlossaryEntry{
+			Source:         t.Source,
+			Translation:    t.Translation,
+			Variants:       t.VariantList(),
+			DoNotTranslate: t.DoNotTranslate,
+			Language:       t.Language,
+		})
+	}
+	g := translate.NewGlossary(entries)
+	c.byArtist[key] = cachedGlossary{glossary: g, loadedAt: time.Now()}
+	return g
+}
+
+// invalidate descarta tudo: termos globais afetam todos os artistas
+func (c *glossaryCache) invalidate() {
+	c.mu.Lock()
+	c.byArtist = make(map[string]cachedGlossary)
+	c.mu.Unlock()
+}
+
+// requireTranslator libera a edição do glossário só para a equipe de tradução: quem tem
+// IsTranslator (concedido por outro tradutor em PUT /api/translators) ou está em TRANSLATORS,
+// a lista de emails que forma a equipe inicial
+func requireTranslator(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, err := CurrentUser(r)
+	if err != nil {
+		http.Error(w, "Não autenticado", http.StatusUnauthorized)
+		return nil, false
+	}
+	if !user.IsTranslator && !envListsEmail("TRANSLATORS", user.Email) {
+		http.Error(w, "Só a equipe de tradução pode editar o glossário", http.StatusForbidden)
+		return nil, false
+	}
+	return user, true
+}
+
+type glossaryRequest struct {
+	Artist         string   `json:"artist"`
+	Source         string   `json:"source"`
+	Translation    string   `json:"translation"`
+	Variants       []string `json:"variants"`
+	DoNotTranslate bool     `json:"do_not_translate"`
+	Language       string   `json:"language"`
+	Notes          string   `json:"notes"`
+}
+
+// apply valida o pedido e copia para o termo
+func (req glossaryRequest) apply(term *models.GlossaryTerm) error {
+	term.Artist = strings.TrimSpace(req.Artist)
+	term.Source = strings.TrimSpace(req.Source)
+	term.Translation = strings.TrimSpace(req.Translation)
+	term.DoNotTranslate = req.DoNotTranslate
+	term.Notes = strings.TrimSpace(req.Notes)
+
+	if term.Source == "" {
+		return errors.New("source é obrigatório")
+	}
+	if term.Translation == "" && !term.DoNotTranslate {
+		return errors.New("translation é obrigatório (ou marque do_not_translate)")
+	}
+
+	term.Language = ""
+	if req.Language != "" {
+		lang, ok := translate.NormalizeLanguage(req.Language)
+		if !ok {
+			return errors.New("idioma não suportado (use pt-BR, en ou es)")
+		}
+		term.Language = lang
+	}
+
+	var variants []string
+	for _, v := range req.Variants {
+		v = strings.TrimSpace(v)
+		if strings.Contains(v, ",") {
+			return errors.New("variantes não podem conter vírgula")
+		}
+		if v != "" {
+			variants = append(variants, v)
+		}
+	}
+	term.Variants = strings.Join(variants, ",")
+	return nil
+}
+
+// ListGlossary lista os termos: GET /api/glossary?artist=BTS
+// Com artist, inclui os termos globais; sem artist, lista tudo.
+func ListGlossary(w http.ResponseWriter, r *http.Request) {
+	if db.DB == nil {
+		http.Error(w, "Banco de dados indisponível", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := db.DB.Order("artist, source")
+	if artist := strings.TrimSpace(r.URL.Query().Get("artist")); artist != "" {
+		query = query.Where("artist = '' OR LOWER(artist) = ?", strings.ToLower(artist))
+	}
+	var terms []models.GlossaryTerm
+	if err := query.Find(&terms).Error; err != nil {
+		http.Error(w, "Erro ao listar glossário", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, terms)
+}
+
+// CreateGlossaryTerm cria um termo: POST /api/glossary
+func CreateGlossaryTerm(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireTranslator(w, r)
+	if !ok {
+		return
+	}
+
+	var req glossaryRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	var term models.GlossaryTerm
+	if err := req.apply(&term); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	term.UpdatedBy = user.ID
+
+	if err := db.DB.Create(&term).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) || strings.Contains(err.Error(), "duplicate") {
+			http.Error(w, "Termo já existe para esse artista e idioma", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Erro ao salvar termo", http.StatusInternalServerError)
+		return
+	}
+	glossaries.invalidate()
+	log.Printf("📖 [Glossário] %s → %s (%s) criado por user %d", term.Source, term.Translation, term.Artist, user.ID)
+	writeJSON(w, http.StatusCreated, term)
+}
+
+// UpdateGlossaryTerm substitui um termo: PUT /api/glossary/{term}
+func UpdateGlossaryTerm(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireTranslator(w, r)
+	if !ok {
+		return
+	}
+	term, ok := loadGlossaryTerm(w, r)
+	if !ok {
+		return
+	}
+
+	var req glossaryRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	if err := req.apply(term); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	term.UpdatedBy = user.ID
+
+	if err := db.DB.Save(term).Error; err != nil {
+		http.Error(w, "Erro ao salvar termo", http.StatusInternalServerError)
+		return
+	}
+	glossaries.invalidate()
+	writeJSON(w, http.StatusOK, term)
+}
+
+// DeleteGlossaryTerm remove um termo: DELETE /api/glossary/{term}
+func DeleteGlossaryTerm(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireTranslator(w, r)
+	if !ok {
+		return
+	}
+	term, ok := loadGlossaryTerm(w, r)
+	if !ok {
+		return
+	}
+
+	if err := db.DB.Delete(term).Error; err != nil {
+		http.Error(w, "Erro ao remover termo", http.StatusInternalServerError)
+		return
+	}
+	glossaries.invalidate()
+	log.Printf("🗑️ [Glossário] Termo %d (%s) removido por user %d", term.ID, term.Source, user.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetTranslator concede ou retira o acesso ao glossário: PUT /api/translators
+// Body: {"email": "...", "translator": true}
+func SetTranslator(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireTranslator(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Email      string `json:"email"`
+		Translator bool   `json:"translator"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+
+	var target models.User
+	if err := db.DB.Where("email = ?", strings.TrimSpace(req.Email)).First(&target).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Usuário não encontrado (precisa ter feito login uma vez)", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Erro ao buscar usuário", http.StatusInternalServerError)
+		return
+	}
+	if err := db.DB.Model(&target).Update("is_translator", req.Translator).Error; err != nil {
+		http.Error(w, "Erro ao salvar usuário", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("📖 [Glossário] User %d marcou user %d como tradutor=%v", user.ID, target.ID, req.Translator)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"user_id": target.ID, "is_translator": req.Translator})
+}
+
+func loadGlossaryTerm(w http.ResponseWriter, r *http.Request) (*models.GlossaryTerm, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["term"], 10, 32)
+	if err != nil || id == 0 {
+		http.Error(w, "ID de termo inválido", http.StatusBadRequest)
+		return nil, false
+	}
+	var term models.GlossaryTerm
+	if err := db.DB.First(&term, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Termo não encontrado", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Erro ao buscar termo", http.StatusInternalServerError)
+		return nil, false
+	}
+	return &term, true
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"k-lens/db"
+	"k-lens/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetTranslatorGrantsGlossaryAccess(t *testing.T) {
+	withTestDB(t, &models.User{}, &models.Session{})
+	t.Setenv("TRANSLATORS", "lider@klens.test")
+	lead := loginAs(t, &models.User{GoogleID: "g-lider", Email: "lider@klens.test"})
+	army := loginAs(t, &models.User{GoogleID: "g-army", Email: "army@klens.test"})
+	loginAs(t, &models.User{GoogleID: "g-novo", Email: "novo@klens.test"})
+
+	grant := func(cookie *http.Cookie, body string) int {
+		req := httptest.NewRequest(http.MethodPut, "/api/translators", strings.NewReader(body))
+		req.AddCookie(cookie)
+		rec := httptest.NewRecorder()
+		SetTranslator(rec, req)
+		return rec.Code
+	}
+
+	if code := grant(army, `{"email":"novo@klens.test","translator":true}`); code != http.StatusForbidden {
+		t.Fatalf("quem não é tradutor não concede acesso: %d", code)
+	}
+	if code := grant(lead, `{"email":"ninguem@klens.test","translator":true}`); code != http.StatusNotFound {
+		t.Fatalf("email sem login: %d", code)
+	}
+	// TRANSLATORS forma a equipe inicial, que concede o acesso aos demais
+	if code := grant(lead, `{"email":"novo@klens.test","translator":true}`); code != http.StatusOK {
+		t.Fatalf("tradutor da equipe inicial deveria conceder: %d", code)
+	}
+	var user models.User
+	db.DB.Where("email = ?", "novo@klens.test").First(&user)
+	if !user.IsTranslator {
+		t.Fatal("IsTranslator não foi gravado")
+	}
+}
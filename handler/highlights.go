@@ -0,0 +1,304 @@
+package handler
+
+import (
+	"errors"
+	"k-lens/db"
+	"k-lens/highlight"
+	"k-lens/media"
+	"k-lens/models"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// highlightRegistry guarda o motor de cortes automáticos de cada live (com o estado
+// de cooldowns e janelas). Editar regras ou ajustes descarta o motor, que é recarregado.
+type highlightRegistry struct {
+	mu      sync.Mutex
+	engines map[uint]*highlight.Engine
+}
+
+var highlights = &highlightRegistry{engines: make(map[uint]*highlight.Engine)}
+
+// engine carrega as regras da live; sem regras próprias valem as padrão
+func (r *highlightRegistry) engine(liveID uint) *highlight.Engine {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.engines[liveID]; ok {
+		return e
+	}
+
+	live, err := lives.Get(liveID)
+	if err != nil {
+		return highlight.NewEngine(nil, 0, false)
+	}
+	rules, err := loadHighlightRules(liveID)
+	if err != nil {
+		log.Printf("⚠️ [Destaques] Erro ao carregar regras da live %d: %v", liveID, err)
+		return highlight.NewEngine(nil, 0, false)
+	}
+	if len(rules) == 0 {
+		rules = highlight.DefaultRules()
+	}
+	e := highlight.NewEngine(rules, live.HighlightThreshold, live.HighlightDryRun)
+	r.engines[liveID] = e
+	return e
+}
+
+func (r *highlightRegistry) invalidate(liveID uint) {
+	r.mu.Lock()
+	delete(r.engines, liveID)
+	r.mu.Unlock()
+}
+
+// Observe passa o sinal ao motor da live e, se der corte, pede o clipe (ou só loga em dry-run).
+// offsetMs é o ponto da live em que o sinal aconteceu.
+func (r *highlightRegistry) Observe(liveID uint, sourceURL string, offsetMs int64, s highlight.Signal) {
+	dec := r.engine(liveID).Observe(s)
+	if dec == nil {
+		return
+	}
+	liveIDStr := strconv.Itoa(int(liveID))
+	if dec.DryRun {
+		log.Printf("🧪 [Destaques] Live %s (dry-run): cortaria em %dms, placar %.2f: %s", liveIDStr, offsetMs, dec.Score, dec.Reason())
+		return
+	}
+	if sourceURL == "" {
+		log.Printf("⚠️ [Destaques] Live %s: corte por %s sem URL da live", liveIDStr, dec.Reason())
+		return
+	}
+	log.Printf("🎬 [Destaques] Live %s: corte em %dms, placar %.2f: %s", liveIDStr, offsetMs, dec.Score, dec.Reason())
+	spec := media.ClipSpec{ClipSettings: clipDefaults.Get(liveIDStr), Label: "highlight", Reason: dec.Reason()}
+	if _, err := videoCutter.CreateClip(liveIDStr, sourceURL, float64(offsetMs), lives.WallClock(liveID, offsetMs), spec); err != nil {
+		log.Printf("❌ [Destaques] Corte não enfileirado: %v", err)
+	}
+}
+
+// forget libera o motor quando a live termina
+func (r *highlightRegistry) forget(liveID uint) {
+	r.invalidate(liveID)
+}
+
+func loadHighlightRules(liveID uint) ([]models.HighlightRule, error) {
+	if db.DB == nil {
+		return nil, nil
+	}
+	var rules []models.HighlightRule
+	err := db.DB.Where("live_archive_id = ?", liveID).Order("id").Find(&rules).Error
+	return rules, err
+}
+
+// ListHighlightRules mostra os ajustes e as regras da live: GET /api/lives/{id}/highlights
+// Sem regras próprias, devolve as padrão com "defaults": true.
+func ListHighlightRules(w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireLiveAction(w, r, id, actionConfig); !ok {
+		return
+	}
+	live, err := lives.Get(id)
+	if err != nil {
+		writeLiveError(w, err)
+		return
+	}
+	rules, err := loadHighlightRules(id)
+	if err != nil {
+		http.Error(w, "Erro ao listar regras", http.StatusInternalServerError)
+		return
+	}
+	defaults := len(rules) == 0
+	if defaults {
+		rules = highlight.DefaultRules()
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"threshold": live.HighlightThreshold,
+		"dry_run":   live.HighlightDryRun,
+		"defaults":  defaults,
+		"rules":     rules,
+	})
+}
+
+// SetHighlightSettings ajusta o placar e o dry-run: PUT /api/lives/{id}/highlights
+// Body: {"threshold": 1.5, "dry_run": true}
+func SetHighlightSettings(w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireLiveAction(w, r, id, actionConfig); !ok {
+		return
+	}
+
+	var req struct {
+		Threshold *float64 `json:"threshold"`
+		DryRun    *bool    `json:"dry_run"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	live, err := lives.Get(id)
+	if err != nil {
+		writeLiveError(w, err)
+		return
+	}
+	if req.Threshold != nil {
+		if *req.Threshold <= 0 {
+			http.Error(w, "threshold deve ser maior que zero", http.StatusBadRequest)
+			return
+		}
+		live.HighlightThreshold = *req.Threshold
+	}
+	if req.DryRun != nil {
+		live.HighlightDryRun = *req.DryRun
+	}
+
+	err = db.DB.Model(&models.LiveArchive{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"highlight_threshold": live.HighlightThreshold,
+		"highlight_dry_run":   live.HighlightDryRun,
+	}).Error
+	if err != nil {
+		http.Error(w, "Erro ao salvar ajustes", http.StatusInternalServerError)
+		return
+	}
+	lives.Put(live)
+	highlights.invalidate(id)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"threshold": live.HighlightThreshold, "dry_run": live.HighlightDryRun})
+}
+
+type highlightRuleRequest struct {
+	Name      string   `json:"name"`
+	Kind      string   `json:"kind"`
+	Field     string   `json:"field"`
+	Pattern   string   `json:"pattern"`
+	Weight    *float64 `json:"weight"` // Ausente = padrão; 0 vale (regra que não soma no placar)
+	Threshold float64  `json:"threshold"`
+	Window    int      `json:"window"`
+	Cooldown  *int     `json:"cooldown"` // Ausente = padrão; 0 vale (sem cooldown)
+	Disabled  bool     `json:"disabled"`
+}
+
+// apply valida o pedido e copia para a regra
+func (req highlightRuleRequest) apply(rule *models.HighlightRule) error {
+	rule.Name, rule.Kind, rule.Field, rule.Pattern = req.Name, req.Kind, req.Field, req.Pattern
+	rule.Threshold, rule.Window = req.Threshold, req.Window
+	rule.Weight, rule.Cooldown = highlight.DefaultWeight, highlight.DefaultCooldown
+	if req.Weight != nil {
+		rule.Weight = *req.Weight
+	}
+	if req.Cooldown != nil {
+		rule.Cooldown = *req.Cooldown
+	}
+	rule.Disabled = req.Disabled
+	return highlight.Normalize(rule)
+}
+
+// CreateHighlightRule cria uma regra: POST /api/lives/{id}/highlights/rules
+// Body: {"name": "grito", "kind": "loudness", "threshold": 15, "weight": 0.5, "cooldown": 120}
+func CreateHighlightRule(w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireLiveAction(w, r, id, actionConfig); !ok {
+		return
+	}
+	if _, err := lives.Get(id); err != nil {
+		writeLiveError(w, err)
+		return
+	}
+
+	var req highlightRuleRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	rule := models.HighlightRule{LiveArchiveID: id}
+	if err := req.apply(&rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := db.DB.Create(&rule).Error; err != nil {
+		http.Error(w, "Erro ao salvar regra", http.StatusInternalServerError)
+		return
+	}
+	highlights.invalidate(id)
+	log.Printf("✨ [Destaques] Regra %q (%s) criada na live %d", rule.Name, rule.Kind, id)
+	writeJSON(w, http.StatusCreated, rule)
+}
+
+// UpdateHighlightRule substitui uma regra: PUT /api/lives/{id}/highlights/rules/{rule}
+func UpdateHighlightRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := loadHighlightRule(w, r)
+	if !ok {
+		return
+	}
+
+	var req highlightRuleRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	if err := req.apply(rule); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := db.DB.Save(rule).Error; err != nil {
+		http.Error(w, "Erro ao salvar regra", http.StatusInternalServerError)
+		return
+	}
+	highlights.invalidate(rule.LiveArchiveID)
+	writeJSON(w, http.StatusOK, rule)
+}
+
+// DeleteHighlightRule remove uma regra: DELETE /api/lives/{id}/highlights/rules/{rule}
+// Sem nenhuma regra própria, a live volta às regras padrão.
+func DeleteHighlightRule(w http.ResponseWriter, r *http.Request) {
+	rule, ok := loadHighlightRule(w, r)
+	if !ok {
+		return
+	}
+	if err := db.DB.Delete(rule).Error; err != nil {
+		http.Error(w, "Erro ao remover regra", http.StatusInternalServerError)
+		return
+	}
+	highlights.invalidate(rule.LiveArchiveID)
+	log.Printf("🗑️ [Destaques] Regra %d removida da live %d", rule.ID, rule.LiveArchiveID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadHighlightRule confere a permissão na live e busca a regra dela
+func loadHighlightRule(w http.ResponseWriter, r *http.Request) (*models.HighlightRule, bool) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if _, ok := requireLiveAction(w, r, id, actionConfig); !ok {
+		return nil, false
+	}
+	ruleID, err := strconv.ParseUint(mux.Vars(r)["rule"], 10, 32)
+	if err != nil || ruleID == 0 {
+		http.Error(w, "ID de regra inválido", http.StatusBadRequest)
+		return nil, false
+	}
+	var rule models.HighlightRule
+	if err := db.DB.Where("live_archive_id = ?", id).First(&rule, ruleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Regra não encontrada", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, "Erro ao buscar regra", http.StatusInternalServerError)
+		return nil, false
+	}
+	return &rule, true
+}
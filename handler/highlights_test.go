@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"encoding/json"
+	"k-lens/highlight"
+	"k-lens/models"
+	"testing"
+)
+
+func TestHighlightRuleRequestKeepsExplicitZero(t *testing.T) {
+	cases := []struct {
+		name     string
+		body     string
+		weight   float64
+		cooldown int
+	}{
+		{"ausentes usam o padrão", `{"kind":"loudness"}`, highlight.DefaultWeight, highlight.DefaultCooldown},
+		{"zero explícito vale", `{"kind":"loudness","weight":0,"cooldown":0}`, 0, 0},
+		{"valores próprios", `{"kind":"loudness","weight":0.5,"cooldown":120}`, 0.5, 120},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var req highlightRuleRequest
+			if err := json.Unmarshal([]byte(tc.body), &req); err != nil {
+				t.Fatal(err)
+			}
+			var rule models.HighlightRule
+			if err := req.apply(&rule); err != nil {
+				t.Fatal(err)
+			}
+			if rule.Weight != tc.weight || rule.Cooldown != tc.cooldown {
+				t.Fatalf("peso %v e cooldown %d, esperava %v e %d", rule.Weight, rule.Cooldown, tc.weight, tc.cooldown)
+			}
+		})
+	}
+}
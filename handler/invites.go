@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"fmt"
+	"k-lens/db"
+	"k-lens/models"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	inviteDefaultTTL = 2 * time.Hour
+	inviteMaxTTL     = 24 * time.Hour
+)
+
+// CreateInvite gera um link de convite de viewer para uma live: POST /api/lives/{id}/invites
+// Body (opcional): {"ttl_minutes": 120, "label": "grupo do discord"}
+func CreateInvite(w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	user, ok := requireLiveAction(w, r, id, actionManage)
+	if !ok {
+		return
+	}
+	// Convidado não emite convite: precisa ser um host logado
+	if user == nil {
+		http.Error(w, "Convites só podem ser criados por um host logado", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		TTLMinutes int    `json:"ttl_minutes"`
+		Label      string `json:"label"`
+	}
+	if r.ContentLength != 0 {
+		if err := decodeJSON(w, r, &req); err != nil {
+			http.Error(w, "JSON inválido", http.StatusBadRequest)
+			return
+		}
+	}
+	ttl := inviteDefaultTTL
+	if req.TTLMinutes > 0 {
+		ttl = time.Duration(req.TTLMinutes) * time.Minute
+	}
+	if ttl > inviteMaxTTL {
+		http.Error(w, fmt.Sprintf("ttl_minutes máximo é %d", int(inviteMaxTTL.Minutes())), http.StatusBadRequest)
+		return
+	}
+
+	subject := req.Label
+	if subject == "" {
+		subject = "invite"
+	}
+	claims := AccessClaims{
+		ID:        randomToken(12),
+		Subject:   subject,
+		Role:      models.RoleViewer,
+		LiveID:    id,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}
+	invite := models.AccessInvite{
+		TokenID:       claims.ID,
+		LiveArchiveID: id,
+		CreatedBy:     user.ID,
+		Role:          claims.Role,
+		ExpiresAt:     time.Unix(claims.ExpiresAt, 0),
+	}
+	if err := db.DB.Create(&invite).Error; err != nil {
+		log.Printf("❌ [Convites] Erro ao salvar convite da live %d: %v", id, err)
+		http.Error(w, "Erro ao criar convite", http.StatusInternalServerError)
+		return
+	}
+
+	token := mintToken(claims)
+	scheme := "http"
+	if r.TLS != nil || (trustProxy() && r.Header.Get("X-Forwarded-Proto") == "https") {
+		scheme = "https"
+	}
+	log.Printf("🎟️ [Convites] Host %d criou convite %d para a live %d (expira em %s)", user.ID, invite.ID, id, ttl)
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"invite": invite,
+		"token":  token,
+		"url":    fmt.Sprintf("%s://%s/studio.html?live=%d&token=%s", scheme, r.Host, id, token),
+	})
+}
+
+// ListInvites lista os convites da live: GET /api/lives/{id}/invites
+func ListInvites(w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireLiveAction(w, r, id, actionManage); !ok {
+		return
+	}
+
+	var invites []models.AccessInvite
+	if err := db.DB.Where("live_archive_id = ?", id).Order("id DESC").Find(&invites).Error; err != nil {
+		http.Error(w, "Erro ao listar convites", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, invites)
+}
+
+// RevokeInvite invalida um convite na hora: DELETE /api/lives/{id}/invites/{invite}
+func RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireLiveAction(w, r, id, actionManage); !ok {
+		return
+	}
+	inviteID, err := strconv.ParseUint(mux.Vars(r)["invite"], 10, 32)
+	if err != nil {
+		http.Error(w, "ID de convite inválido", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	res := db.DB.Model(&models.AccessInvite{}).
+		Where("id = ? AND live_archive_id = ? AND revoked_at IS NULL", inviteID, id).
+		Update("revoked_at", now)
+	if res.Error != nil {
+		http.Error(w, "Erro ao revogar convite", http.StatusInternalServerError)
+		return
+	}
+	if res.RowsAffected == 0 {
+		http.Error(w, "Convite não encontrado ou já revogado", http.StatusNotFound)
+		return
+	}
+	log.Printf("🚫 [Convites] Convite %d da live %d revogado", inviteID, id)
+	w.WriteHeader(http.StatusNoContent)
+}
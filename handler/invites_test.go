@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"encoding/json"
+	"k-lens/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestCreateInviteTrustsForwardedProtoOnlyBehindProxy(t *testing.T) {
+	withTestDB(t, &models.User{}, &models.Session{}, &models.LiveMember{}, &models.AccessInvite{})
+	host := &models.User{GoogleID: "g-host", Email: "host@klens.test"}
+	cookie := loginAs(t, host)
+	setLiveRole(1, host.ID, models.RoleHost)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/lives/{id}/invites", CreateInvite).Methods("POST")
+
+	cases := []struct {
+		name  string
+		trust string
+		want  string
+	}{
+		{"sem proxy confiável", "", "http://klens.test/"},
+		{"atrás de proxy confiável", "true", "https://klens.test/"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("RATE_TRUST_PROXY", tc.trust)
+			req := httptest.NewRequest(http.MethodPost, "http://klens.test/api/lives/1/invites", nil)
+			req.Header.Set("X-Forwarded-Proto", "https")
+			req.AddCookie(cookie)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			var resp struct {
+				URL string `json:"url"`
+			}
+			json.Unmarshal(rec.Body.Bytes(), &resp)
+			if rec.Code != http.StatusCreated || !strings.HasPrefix(resp.URL, tc.want) {
+				t.Fatalf("status %d, url %q, esperava prefixo %q", rec.Code, resp.URL, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"k-lens/db"
+	"k-lens/media"
+	"k-lens/models"
+	"log"
+	"strconv"
+	"sync"
+)
+
+// liveSettings guarda os padrões de corte por live: cache em memória
+// com persistência na própria LiveArchive.
+type liveSettings struct {
+	mu     sync.Mutex
+	byLive map[string]models.ClipSettings
+}
+
+var clipDefaults = &liveSettings{byLive: make(map[string]models.ClipSettings)}
+
+// Get retorna os padrões da live (cache -> banco -> DefaultClipSettings)
+func (s *liveSettings) Get(liveID string) models.ClipSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cs, ok := s.byLive[liveID]; ok {
+		return cs
+	}
+
+	cs := media.DefaultClipSettings()
+	if id, err := strconv.ParseUint(liveID, 10, 32); err == nil && db.DB != nil {
+		var live models.LiveArchive
+		if db.DB.First(&live, id).Error == nil {
+			cs = media.NormalizeSettings(live.ClipDefaults)
+		}
+	}
+	s.byLive[liveID] = cs
+	return cs
+}
+
+// Set valida e grava os novos padrões da live
+func (s *liveSettings) Set(liveID string, cs models.ClipSettings) (models.ClipSettings, error) {
+	cs = media.NormalizeSettings(cs)
+	if err := media.ValidateSettings(cs); err != nil {
+		return cs, err
+	}
+
+	s.mu.Lock()
+	s.byLive[liveID] = cs
+	s.mu.Unlock()
+
+	if id, err := strconv.ParseUint(liveID, 10, 32); err == nil && db.DB != nil {
+		err := db.DB.Model(&models.LiveArchive{}).Where("id = ?", id).
+			Select("clip_duration", "clip_pre_roll", "clip_aspect_ratio", "clip_watermark", "clip_quality",
+				"clip_sub_mode", "clip_sub_font", "clip_sub_size", "clip_sub_position", "clip_sub_language").
+			Updates(&models.LiveArchive{ClipDefaults: cs}).Error
+		if err != nil {
+			log.Printf("⚠️ [Config] Erro ao salvar padrões da live %s: %v", liveID, err)
+		}
+	}
+	return cs, nil
+}
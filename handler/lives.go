@@ -0,0 +1,562 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"k-lens/db"
+	"k-lens/hub"
+	"k-lens/models"
+	"k-lens/translate"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+var errLiveNotFound = errors.New("live não encontrada")
+
+// liveRegistry mantém em memória o estado canônico das lives (início/fim),
+// para que todas as conexões de uma live usem o mesmo relógio.
+type liveRegistry struct {
+	mu   sync.Mutex
+	byID map[uint]models.LiveArchive
+}
+
+var lives = &liveRegistry{byID: make(map[uint]models.LiveArchive)}
+
+// Get busca a live no cache ou no banco
+func (l *liveRegistry) Get(id uint) (models.LiveArchive, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if live, ok := l.byID[id]; ok {
+		return live, nil
+	}
+	if db.DB == nil {
+		return models.LiveArchive{}, fmt.Errorf("banco de dados indisponível")
+	}
+
+	var live models.LiveArchive
+	if err := db.DB.First(&live, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return live, errLiveNotFound
+		}
+		return live, err
+	}
+	l.byID[id] = live
+	return live, nil
+}
+
+func (l *liveRegistry) Put(live models.LiveArchive) {
+	l.mu.Lock()
+	l.byID[live.ID] = live
+	l.mu.Unlock()
+}
+
+// Offset retorna os milissegundos desde o início oficial da live (false se ainda não começou)
+func (l *liveRegistry) Offset(id uint, at time.Time) (int64, bool) {
+	live, err := l.Get(id)
+	if err != nil || live.StartedAt == nil {
+		return 0, false
+	}
+	return at.Sub(*live.StartedAt).Milliseconds(), true
+}
+
+// WallClock converte um offset da live de volta para horário de parede
+func (l *liveRegistry) WallClock(id uint, offsetMs int64) time.Time {
+	live, err := l.Get(id)
+	if err != nil || live.StartedAt == nil {
+		return time.Now()
+	}
+	return live.StartedAt.Add(time.Duration(offsetMs) * time.Millisecond)
+}
+
+// Languages devolve os idiomas de legenda da live (o primeiro é o principal)
+func (l *liveRegistry) Languages(id uint) []string {
+	live, err := l.Get(id)
+	if err != nil {
+		return []string{translate.DefaultLanguage}
+	}
+	langs, err := translate.NormalizeLanguages(live.LanguageList())
+	if err != nil {
+		return []string{translate.DefaultLanguage}
+	}
+	return langs
+}
+
+// SetLanguages valida e grava os idiomas de legenda da live
+func (l *liveRegistry) SetLanguages(id uint, codes []string) ([]string, error) {
+	langs, err := translate.NormalizeLanguages(codes)
+	if err != nil {
+		return nil, err
+	}
+	live, err := l.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	live.Languages = strings.Join(langs, ",")
+	if err := db.DB.Model(&models.LiveArchive{}).Where("id = ?", id).Update("languages", live.Languages).Error; err != nil {
+		return nil, err
+	}
+	l.Put(live)
+	return langs, nil
+}
+
+// Roster devolve os membros da live
+func (l *liveRegistry) Roster(id uint) []string {
+	live, err := l.Get(id)
+	if err != nil {
+		return nil
+	}
+	return live.RosterList()
+}
+
+// SetRoster valida e grava o elenco da live
+func (l *liveRegistry) SetRoster(id uint, names []string) ([]string, error) {
+	roster, err := normalizeRoster(names)
+	if err != nil {
+		return nil, err
+	}
+	live, err := l.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	live.Roster = strings.Join(roster, ",")
+	if err := db.DB.Model(&models.LiveArchive{}).Where("id = ?", id).Update("roster", live.Roster).Error; err != nil {
+		return nil, err
+	}
+	l.Put(live)
+	return roster, nil
+}
+
+// ReviewDelay devolve a retenção para revisão da live (0 = publica na hora)
+func (l *liveRegistry) ReviewDelay(id uint) time.Duration {
+	live, err := l.Get(id)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(live.ReviewDelay) * time.Second
+}
+
+// SetReviewDelay valida e grava a retenção para revisão, em segundos
+func (l *liveRegistry) SetReviewDelay(id uint, seconds int) (int, error) {
+	if err := validateReviewDelay(seconds); err != nil {
+		return 0, err
+	}
+	live, err := l.Get(id)
+	if err != nil {
+		return 0, err
+	}
+	live.ReviewDelay = seconds
+	if err := db.DB.Model(&models.LiveArchive{}).Where("id = ?", id).Update("review_delay", seconds).Error; err != nil {
+		return 0, err
+	}
+	l.Put(live)
+	return seconds, nil
+}
+
+func validateReviewDelay(seconds int) error {
+	if seconds < 0 || time.Duration(seconds)*time.Second > maxReviewDelay {
+		return fmt.Errorf("review_delay deve ficar entre 0 e %d segundos", int(maxReviewDelay.Seconds()))
+	}
+	return nil
+}
+
+// Limites do elenco: cabe no prompt e na legenda
+const (
+	maxRoster     = 30
+	maxMemberName = 40
+)
+
+func normalizeRoster(names []string) ([]string, error) {
+	var out []string
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		if len([]rune(name)) > maxMemberName || strings.ContainsAny(name, ",[]") {
+			return nil, fmt.Errorf("nome de membro inválido: %q", name)
+		}
+		seen[strings.ToLower(name)] = true
+		out = append(out, name)
+	}
+	if len(out) > maxRoster {
+		return nil, fmt.Errorf("elenco com mais de %d membros", maxRoster)
+	}
+	return out, nil
+}
+
+// parseLiveID valida o {id} da rota
+func parseLiveID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("ID de live inválido: %q", mux.Vars(r)["id"])
+	}
+	return uint(id), nil
+}
+
+// CreateLive registra uma nova live: POST /api/lives
+func CreateLive(w http.ResponseWriter, r *http.Request) {
+	if db.DB == nil {
+		http.Error(w, "Banco de dados indisponível", http.StatusServiceUnavailable)
+		return
+	}
+
+	user, err := CurrentUser(r)
+	if err != nil {
+		http.Error(w, "Não autenticado", http.StatusUnauthorized)
+		return
+	}
+	if !canCreateLive(user) {
+		http.Error(w, "Permissão negada", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		Title     string   `json:"title"`
+		IdolName  string   `json:"idol_name"`
+		Platform  string   `json:"platform"`
+		SourceURL string   `json:"source_url"`
+		Languages []string `json:"languages"` // Ex: ["pt-BR", "en", "es"]
+		Roster    []string `json:"roster"`    // Ex: ["RM", "Jin", "Suga"]
+
+		ReviewDelay int `json:"review_delay"` // Segundos de retenção para revisão
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		http.Error(w, "title é obrigatório", http.StatusBadRequest)
+		return
+	}
+	if req.SourceURL != "" && !strings.HasPrefix(req.SourceURL, "http://") && !strings.HasPrefix(req.SourceURL, "https://") {
+		http.Error(w, "source_url deve ser http(s)", http.StatusBadRequest)
+		return
+	}
+	langs, err := translate.NormalizeLanguages(req.Languages)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	roster, err := normalizeRoster(req.Roster)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateReviewDelay(req.ReviewDelay); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	live := models.LiveArchive{
+		Title:     req.Title,
+		IdolName:  strings.TrimSpace(req.IdolName),
+		Platform:  strings.TrimSpace(req.Platform),
+		SourceURL: req.SourceURL,
+		Languages: strings.Join(langs, ","),
+		Roster:    strings.Join(roster, ","),
+		Status:    models.LiveCreated,
+
+		ReviewDelay: req.ReviewDelay,
+	}
+	if err := db.DB.Create(&live).Error; err != nil {
+		log.Printf("❌ [Lives] Erro ao criar live: %v", err)
+		http.Error(w, "Erro ao criar live", http.StatusInternalServerError)
+		return
+	}
+	lives.Put(live)
+
+	// Quem cria a live é o host dela
+	if err := setLiveRole(live.ID, user.ID, models.RoleHost); err != nil {
+		log.Printf("⚠️ [Lives] Erro ao definir host da live %d: %v", live.ID, err)
+	}
+
+	writeJSON(w, http.StatusCreated, live)
+}
+
+// canCreateLive libera a criação de lives para a equipe: emails em LIVE_CREATORS
+// (separados por vírgula) ou quem já é host de alguma live
+func canCreateLive(user *models.User) bool {
+	if envListsEmail("LIVE_CREATORS", user.Email) {
+		return true
+	}
+	var count int64
+	db.DB.Model(&models.LiveMember{}).Where("user_id = ? AND role = ?", user.ID, models.RoleHost).Count(&count)
+	return count > 0
+}
+
+// isLiveMember indica se o usuário tem algum papel registrado na live
+func isLiveMember(liveID, userID uint) bool {
+	var count int64
+	db.DB.Model(&models.LiveMember{}).Where("live_archive_id = ? AND user_id = ?", liveID, userID).Count(&count)
+	return count > 0
+}
+
+// ListLives lista as lives que o chamador enxerga: GET /api/lives?status=live
+// Com sessão, as lives em que ele tem papel; com token, só a live do convite.
+func ListLives(w http.ResponseWriter, r *http.Request) {
+	if db.DB == nil {
+		http.Error(w, "Banco de dados indisponível", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := db.DB.Order("id DESC").Limit(100)
+	if user, err := CurrentUser(r); err == nil {
+		query = query.Where("id IN (?)", db.DB.Model(&models.LiveMember{}).Select("live_archive_id").Where("user_id = ?", user.ID))
+	} else if claims := requestClaims(r); claims != nil {
+		query = query.Where("id = ?", claims.LiveID)
+	} else {
+		http.Error(w, "Não autenticado", http.StatusUnauthorized)
+		return
+	}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var result []models.LiveArchive
+	if err := query.Find(&result).Error; err != nil {
+		http.Error(w, "Erro ao listar lives", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// GetLive busca uma live: GET /api/lives/{id}
+// Só para quem tem papel na live ou um token com escopo nela.
+func GetLive(w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	user, _, err := liveAccess(r, id)
+	if err != nil {
+		status, msg := accessStatus(err)
+		http.Error(w, msg, status)
+		return
+	}
+	if user != nil && !isLiveMember(id, user.ID) {
+		http.Error(w, "Permissão negada", http.StatusForbidden)
+		return
+	}
+	live, err := lives.Get(id)
+	if err != nil {
+		writeLiveError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, live)
+}
+
+// SetLiveLanguages define os idiomas de legenda: PUT /api/lives/{id}/languages
+// Body: {"languages": ["pt-BR", "en", "es"]}
+func SetLiveLanguages(w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireLiveAction(w, r, id, actionConfig); !ok {
+		return
+	}
+
+	var req struct {
+		Languages []string `json:"languages"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	langs, err := lives.SetLanguages(id, req.Languages)
+	if err != nil {
+		if errors.Is(err, errLiveNotFound) {
+			writeLiveError(w, err)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"languages": langs})
+}
+
+// SetLiveRoster define os membros da live: PUT /api/lives/{id}/roster
+// Body: {"roster": ["RM", "Jin", "Suga"]}
+func SetLiveRoster(w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireLiveAction(w, r, id, actionConfig); !ok {
+		return
+	}
+
+	var req struct {
+		Roster []string `json:"roster"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	roster, err := lives.SetRoster(id, req.Roster)
+	if err != nil {
+		if errors.Is(err, errLiveNotFound) {
+			writeLiveError(w, err)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"roster": roster})
+}
+
+// SetLiveReviewDelay liga/desliga a revisão antes da publicação: PUT /api/lives/{id}/review_delay
+// Body: {"review_delay": 10}
+func SetLiveReviewDelay(w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireLiveAction(w, r, id, actionConfig); !ok {
+		return
+	}
+
+	var req struct {
+		ReviewDelay int `json:"review_delay"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	delay, err := lives.SetReviewDelay(id, req.ReviewDelay)
+	if err != nil {
+		if errors.Is(err, errLiveNotFound) {
+			writeLiveError(w, err)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"review_delay": delay})
+}
+
+// StartLive marca o início oficial: POST /api/lives/{id}/start
+// O horário é o do servidor e vira a origem dos offsets de legendas e cortes.
+func StartLive(h *hub.Hub, w http.ResponseWriter, r *http.Request) {
+	live, ok := transitionLive(w, r, models.LiveCreated, func(live *models.LiveArchive, now time.Time) {
+		live.Status = models.LiveOn
+		live.StartedAt = &now
+	})
+	if !ok {
+		return
+	}
+
+	if live.SourceURL != "" {
+		videoCutter.StartRecording(strconv.Itoa(int(live.ID)), live.SourceURL)
+	}
+	broadcastLiveStatus(h, live)
+	writeJSON(w, http.StatusOK, live)
+}
+
+// EndLive encerra a live: POST /api/lives/{id}/end
+func EndLive(h *hub.Hub, w http.ResponseWriter, r *http.Request) {
+	live, ok := transitionLive(w, r, models.LiveOn, func(live *models.LiveArchive, now time.Time) {
+		live.Status = models.LiveEnded
+		live.EndedAt = &now
+	})
+	if !ok {
+		return
+	}
+
+	videoCutter.DVR.Stop(strconv.Itoa(int(live.ID)))
+	// O que ainda aguardava revisão vai ao ar antes de fechar o feed
+	reviews.flush(h, strconv.Itoa(int(live.ID)))
+	tiers.forget(strconv.Itoa(int(live.ID)))
+	contexts.forget(strconv.Itoa(int(live.ID)))
+	voices.forget(strconv.Itoa(int(live.ID)))
+	highlights.forget(live.ID)
+	broadcastLiveStatus(h, live)
+	writeJSON(w, http.StatusOK, live)
+}
+
+// transitionLive aplica uma mudança de estado validando o estado de origem
+func transitionLive(w http.ResponseWriter, r *http.Request, from string, apply func(*models.LiveArchive, time.Time)) (models.LiveArchive, bool) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return models.LiveArchive{}, false
+	}
+	if _, ok := requireLiveAction(w, r, id, actionManage); !ok {
+		return models.LiveArchive{}, false
+	}
+
+	lives.mu.Lock()
+	defer lives.mu.Unlock()
+
+	if db.DB == nil {
+		http.Error(w, "Banco de dados indisponível", http.StatusServiceUnavailable)
+		return models.LiveArchive{}, false
+	}
+	var live models.LiveArchive
+	if err := db.DB.First(&live, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = errLiveNotFound
+		}
+		writeLiveError(w, err)
+		return live, false
+	}
+	if live.Status != from {
+		http.Error(w, fmt.Sprintf("Live está em %q, esperado %q", live.Status, from), http.StatusConflict)
+		return live, false
+	}
+
+	apply(&live, time.Now())
+	if err := db.DB.Save(&live).Error; err != nil {
+		log.Printf("❌ [Lives] Erro ao salvar live %d: %v", live.ID, err)
+		http.Error(w, "Erro ao salvar live", http.StatusInternalServerError)
+		return live, false
+	}
+	lives.byID[live.ID] = live
+	log.Printf("📺 [Lives] Live %d agora está %s", live.ID, live.Status)
+	return live, true
+}
+
+func broadcastLiveStatus(h *hub.Hub, live models.LiveArchive) {
+	h.Broadcast <- hub.Message{
+		Type: "live_status",
+		Payload: map[string]interface{}{
+			"status":     live.Status,
+			"started_at": live.StartedAt,
+			"ended_at":   live.EndedAt,
+		},
+		LiveID: strconv.Itoa(int(live.ID)),
+	}
+}
+
+func writeLiveError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errLiveNotFound) {
+		http.Error(w, "Live não encontrada", http.StatusNotFound)
+		return
+	}
+	http.Error(w, "Erro ao buscar live", http.StatusInternalServerError)
+}
+
+// decodeJSON lê o body com limite de tamanho
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	return json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(v)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
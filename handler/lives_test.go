@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"encoding/json"
+	"k-lens/db"
+	"k-lens/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func livesRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.Use(SecurityMiddleware)
+	router.HandleFunc("/api/lives", CreateLive).Methods("POST")
+	router.HandleFunc("/api/lives", ListLives).Methods("GET")
+	router.HandleFunc("/api/lives/{id}", GetLive).Methods("GET")
+	return router
+}
+
+func livesRequest(router http.Handler, method, path, body string, cookie *http.Cookie, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCreateLiveRequiresCreator(t *testing.T) {
+	withTestDB(t, &models.User{}, &models.Session{}, &models.LiveMember{}, &models.LiveArchive{}, &models.AccessInvite{})
+	t.Setenv("LIVE_CREATORS", "equipe@klens.test, outra@klens.test")
+	router := livesRouter()
+
+	staff := loginAs(t, &models.User{GoogleID: "g-staff", Email: "Equipe@klens.test"})
+	viewer := loginAs(t, &models.User{GoogleID: "g-viewer", Email: "army@klens.test", IsVIP: true})
+
+	if rec := livesRequest(router, "POST", "/api/lives", `{"title":"Live"}`, viewer, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("viewer não pode criar live: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := livesRequest(router, "POST", "/api/lives", `{"title":"Live"}`, staff, ""); rec.Code != http.StatusCreated {
+		t.Fatalf("email da allowlist deveria criar: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Quem já é host de uma live cria as próximas sem estar na allowlist
+	t.Setenv("LIVE_CREATORS", "")
+	if rec := livesRequest(router, "POST", "/api/lives", `{"title":"Outra"}`, staff, ""); rec.Code != http.StatusCreated {
+		t.Fatalf("host deveria criar: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestListAndGetLivesFilterByAccess(t *testing.T) {
+	withTestDB(t, &models.User{}, &models.Session{}, &models.LiveMember{}, &models.LiveArchive{}, &models.AccessInvite{})
+	router := livesRouter()
+	for _, title := range []string{"Um", "Dois", "Três"} {
+		db.DB.Create(&models.LiveArchive{Title: title})
+	}
+
+	member := &models.User{GoogleID: "g-mod", Email: "mod@klens.test"}
+	memberCookie := loginAs(t, member)
+	setLiveRole(2, member.ID, models.RoleModerator)
+	stranger := loginAs(t, &models.User{GoogleID: "g-outro", Email: "outro@klens.test"})
+	invite := inviteToken(t, 3, models.RoleViewer)
+
+	list := func(cookie *http.Cookie, token string) []uint {
+		rec := livesRequest(router, "GET", "/api/lives", "", cookie, token)
+		var result []models.LiveArchive
+		json.Unmarshal(rec.Body.Bytes(), &result)
+		var ids []uint
+		for _, l := range result {
+			ids = append(ids, l.ID)
+		}
+		return ids
+	}
+	if ids := list(memberCookie, ""); len(ids) != 1 || ids[0] != 2 {
+		t.Fatalf("membro deveria ver só a live 2: %v", ids)
+	}
+	if ids := list(stranger, ""); len(ids) != 0 {
+		t.Fatalf("sem papel não vê nenhuma live: %v", ids)
+	}
+	if ids := list(nil, invite); len(ids) != 1 || ids[0] != 3 {
+		t.Fatalf("convite deveria ver só a live 3: %v", ids)
+	}
+
+	cases := []struct {
+		name   string
+		path   string
+		cookie *http.Cookie
+		token  string
+		status int
+	}{
+		{"membro na própria live", "/api/lives/2", memberCookie, "", http.StatusOK},
+		{"membro em outra live", "/api/lives/1", memberCookie, "", http.StatusForbidden},
+		{"sessão sem papel", "/api/lives/2", stranger, "", http.StatusForbidden},
+		{"convite da live", "/api/lives/3", nil, invite, http.StatusOK},
+		{"convite de outra live", "/api/lives/2", nil, invite, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if rec := livesRequest(router, "GET", tc.path, "", tc.cookie, tc.token); rec.Code != tc.status {
+				t.Fatalf("status %d, esperava %d: %s", rec.Code, tc.status, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"k-lens/highlight"
+	"k-lens/hub"
+	"k-lens/models"
+	"k-lens/translate"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// captionPipeline traduz as falas que chegam por uma conexão de áudio e publica
+// as legendas na sala da live (parciais, finais, revisão, arquivo e gatilhos de corte)
+type captionPipeline struct {
+	hub        *hub.Hub
+	translator translate.Translator
+	client     *hub.Client // Recebe os avisos de trecho descartado
+	live       models.LiveArchive
+	sourceURL  func() string // URL atual da live (muda com o update_config)
+}
+
+// translateSegment envia uma fala completa ao tradutor e distribui o resultado
+// voice é o grupo de voz local da fala (-1 quando não há agrupamento)
+func (p *captionPipeline) translateSegment(blob []byte, mimeType string, startMs, endMs int64, voice int) {
+	h, liveID, liveIDStr := p.hub, p.live.ID, strconv.Itoa(int(p.live.ID))
+	select {
+	case semaphore <- struct{}{}:
+		defer func() { <-semaphore }()
+	default:
+		translateDropped.Add(1)
+		log.Printf("⚠️ [Tradutor] Fila cheia, descartando fala %d-%dms", startMs, endMs)
+		h.SendTo(p.client, hub.Message{Type: "error", Payload: "Tradutor sobrecarregado: trecho descartado", LiveID: liveIDStr})
+		return
+	}
+
+	// Timeout aumentado para 30 segundos para melhor robustez
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	log.Printf("⏱️ [Tradutor] Processando fala %d-%dms com timeout de 30s", startMs, endMs)
+
+	targets := lives.Languages(liveID)
+	glossary := glossaries.For(p.live.IdolName)
+
+	// Com revisão ligada, nada sai antes do moderador: parciais só para a equipe
+	reviewDelay := lives.ReviewDelay(liveID)
+	partialAudience := hub.AudienceVIP
+	if reviewDelay > 0 {
+		partialAudience = hub.AudienceStaff
+	}
+
+	// Parciais (só feed VIP) e a final compartilham o mesmo caption_id
+	captionID := randomToken(9)
+	roster := lives.Roster(liveID)
+	hint := voices.Hint(liveIDStr, voice)
+	lastPartial := map[string]string{}
+	onPartial := func(partial translate.Partial) {
+		speaker := resolveSpeaker(roster, partial.Speaker)
+		if speaker == "" {
+			speaker = hint
+		}
+		for _, lang := range targets {
+			text := glossary.Enforce(partial.Captions[lang], lang)
+			if text == "" {
+				continue
+			}
+			text = models.WithSpeaker(speaker, text)
+			if text == lastPartial[lang] {
+				continue
+			}
+			lastPartial[lang] = text
+			h.Broadcast <- hub.Message{
+				Type: "caption_partial", Payload: text, LiveID: liveIDStr, StartMs: startMs, EndMs: endMs,
+				Language: lang, CaptionID: captionID, Speaker: speaker, Audience: partialAudience,
+			}
+		}
+	}
+
+	result, err := p.translator.TranslateAudio(ctx, translate.AudioRequest{
+		Audio: blob, MIMEType: mimeType, Targets: targets, Glossary: glossary.Entries,
+		Context: contexts.Window(liveIDStr), Roster: roster, SpeakerHint: hint, OnPartial: onPartial,
+	})
+	if err != nil {
+		log.Printf("❌ [Tradutor] Erro na tradução de áudio: %v", err)
+		// Parcial vazia = descartar o que já apareceu na tela
+		for lang := range lastPartial {
+			h.Broadcast <- hub.Message{
+				Type: "caption_partial", Payload: "", LiveID: liveIDStr, Language: lang, CaptionID: captionID, Audience: partialAudience,
+			}
+		}
+		return
+	}
+
+	// O nome do modelo ensina o grupo de voz; sem nome, vale o que o grupo já aprendeu
+	speaker := resolveSpeaker(roster, result.Speaker)
+	voices.Vote(liveIDStr, voice, speaker)
+	if speaker == "" {
+		speaker = hint
+	}
+
+	// Uma mensagem por idioma, na ordem da live (o principal primeiro)
+	var msgs []hub.Message
+	var texts []string
+	for _, lang := range targets {
+		// O modelo recebe o glossário, mas a grafia final é garantida aqui
+		text := glossary.Enforce(result.Captions[lang], lang)
+		if text == "" {
+			continue
+		}
+		msgs = append(msgs, hub.Message{
+			Type: "caption_final", Payload: models.WithSpeaker(speaker, text), LiveID: liveIDStr,
+			StartMs: startMs, EndMs: endMs, Language: lang, CaptionID: captionID, Speaker: speaker,
+		})
+		texts = append(texts, text)
+	}
+	if len(msgs) == 0 {
+		log.Printf("⚠️ [Tradutor] Resposta vazia")
+		return
+	}
+
+	// Publicação (na hora ou depois da revisão); o contexto e os gatilhos usam o texto final
+	reviews.Submit(h, &pendingCaption{
+		LiveID: liveIDStr, CaptionID: captionID, StartMs: startMs, Speaker: speaker, Msgs: msgs, Texts: texts,
+		onPublished: func(final []string, speaker string) {
+			tiers.publishTranscript(h, hub.Message{
+				Type: "transcript", Payload: result.Transcript, LiveID: liveIDStr,
+				StartMs: startMs, EndMs: endMs, CaptionID: captionID, Speaker: speaker,
+			})
+			contexts.Add(liveIDStr, translate.ContextTurn{Speaker: speaker, Transcript: result.Transcript, Caption: final[0]})
+
+			highlights.Observe(liveID, p.sourceURL(), startMs, highlight.Signal{
+				Kind: highlight.SignalCaption, Translation: strings.Join(final, "\n"), Transcript: result.Transcript,
+			})
+		},
+	}, reviewDelay)
+}
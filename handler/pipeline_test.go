@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"k-lens/hub"
+	"k-lens/models"
+	"k-lens/translate"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testRoom sobe um hub com um cliente VIP na sala da live
+func testRoom(t *testing.T, live models.LiveArchive, lang string) (*hub.Hub, *hub.Client) {
+	t.Helper()
+	lives.Put(live)
+	h := hub.NewHub()
+	go h.Run()
+	client := hub.NewClient(strconv.Itoa(int(live.ID)))
+	client.VIP = true
+	client.Language = lang
+	h.Register <- client
+	return h, client
+}
+
+// receive lê a próxima mensagem do cliente (ou falha no timeout)
+func receive(t *testing.T, client *hub.Client) hub.Message {
+	t.Helper()
+	select {
+	case msg := <-client.Send:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("nenhuma mensagem recebida")
+		return hub.Message{}
+	}
+}
+
+func TestTranslateSegmentWithFakeService(t *testing.T) {
+	live := models.LiveArchive{ID: 9101, Languages: "pt-BR,en", Roster: "Jimin"}
+	h, client := testRoom(t, live, "pt-BR")
+	free := hub.NewClient(strconv.Itoa(int(live.ID)))
+	h.Register <- free
+	p := &captionPipeline{
+		hub: h, translator: translate.NewFakeService(), client: client, live: live,
+		sourceURL: func() string { return "" },
+	}
+
+	p.translateSegment([]byte("fala de teste"), "audio/wav", 1000, 2500, -1)
+
+	var partials []hub.Message
+	var final hub.Message
+	for final.Type == "" {
+		msg := receive(t, client)
+		switch msg.Type {
+		case "caption_partial":
+			partials = append(partials, msg)
+		case "caption_final":
+			final = msg
+		default:
+			t.Fatalf("mensagem inesperada: %+v", msg)
+		}
+	}
+
+	if len(partials) == 0 {
+		t.Fatal("nenhuma parcial antes da final")
+	}
+	for _, msg := range append(partials, final) {
+		if msg.Language != "pt-BR" {
+			t.Fatalf("cliente pt-BR recebeu legenda em %q", msg.Language)
+		}
+		if msg.CaptionID == "" || msg.CaptionID != final.CaptionID {
+			t.Fatalf("caption_id diferente entre parcial e final: %q x %q", msg.CaptionID, final.CaptionID)
+		}
+		if msg.StartMs != 1000 || msg.EndMs != 2500 || msg.Speaker != "Jimin" {
+			t.Fatalf("metadados inesperados: %+v", msg)
+		}
+	}
+	text, _ := final.Payload.(string)
+	if !strings.HasPrefix(text, "[Jimin] [fake pt-BR] fala de 13 bytes (audio/wav)") {
+		t.Fatalf("final inesperada: %q", text)
+	}
+	last, _ := partials[len(partials)-1].Payload.(string)
+	if !strings.HasPrefix(text, last) {
+		t.Fatalf("parcial %q não é prefixo da final %q", last, text)
+	}
+
+	// A transcrição completa vem depois da final, só no feed VIP
+	transcript := receive(t, client)
+	if payload, _ := transcript.Payload.(string); transcript.Type != "transcript" || !strings.HasPrefix(payload, "[fake ko]") || transcript.CaptionID != final.CaptionID {
+		t.Fatalf("transcrição inesperada: %+v", transcript)
+	}
+	select {
+	case msg := <-free.Send:
+		t.Fatalf("feed gratuito recebeu %q em tempo real", msg.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"k-lens/ratelimit"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// rateGuard combina um bucket por identidade (usuário ou convite) e outro por IP.
+// Configurável por RATE_<NOME> e RATE_<NOME>_IP (ex: "10/m", "50/s:100", "off").
+type rateGuard struct {
+	user *ratelimit.Limiter
+	ip   *ratelimit.Limiter
+}
+
+func newRateGuard(name, userDefault, ipDefault string) *rateGuard {
+	env := "RATE_" + strings.ToUpper(name)
+	return &rateGuard{
+		user: ratelimit.New(name+"_user", envLimit(env, userDefault)),
+		ip:   ratelimit.New(name+"_ip", envLimit(env+"_IP", ipDefault)),
+	}
+}
+
+// allow exige ficha nos dois buckets; identidade vazia (anônimo) só passa pelo de IP
+func (g *rateGuard) allow(identity, ip string) bool {
+	return g.user.Allow(identity) && g.ip.Allow(ip)
+}
+
+type rateLimits struct {
+	once     sync.Once
+	reverse  *rateGuard // POST /api/translate-reverse
+	audio    *rateGuard // Frames binários por segundo no WebSocket
+	clip     *rateGuard // MANUAL_CLIP
+	connect  *rateGuard // Novas conexões WebSocket
+	reaction *rateGuard // Reações do público no WebSocket
+}
+
+var limits = &rateLimits{}
+
+func (l *rateLimits) load() *rateLimits {
+	l.once.Do(func() {
+		l.reverse = newRateGuard("reverse", "10/m", "30/m")
+		l.audio = newRateGuard("audio", "50/s:100", "150/s:300")
+		l.clip = newRateGuard("clip", "6/m", "20/m")
+		l.connect = newRateGuard("connect", "10/m", "30/m")
+		l.reaction = newRateGuard("reaction", "2/s:5", "20/s:40")
+	})
+	return l
+}
+
+// translateDropped conta falas descartadas porque o semáforo do tradutor estava cheio
+var translateDropped atomic.Uint64
+
+func envLimit(name, def string) ratelimit.Limit {
+	v := os.Getenv(name)
+	if v == "" {
+		v = def
+	}
+	limit, err := ratelimit.ParseLimit(v)
+	if err != nil {
+		log.Printf("⚠️ [RateLimit] %s inválido (%v), usando %s", name, err, def)
+		limit, _ = ratelimit.ParseLimit(def)
+	}
+	return limit
+}
+
+// identityKey identifica quem está pedindo: usuário logado ou token de convite
+func identityKey(r *http.Request) string {
+	if user, err := CurrentUser(r); err == nil {
+		return "u:" + strconv.FormatUint(uint64(user.ID), 10)
+	}
+	if claims := requestClaims(r); claims != nil {
+		return "t:" + claims.ID
+	}
+	return ""
+}
+
+// trustProxy indica que o servidor roda atrás de proxy confiável (RATE_TRUST_PROXY=true):
+// só então os headers X-Forwarded-* valem
+func trustProxy() bool {
+	return os.Getenv("RATE_TRUST_PROXY") == "true"
+}
+
+// clientIP usa o RemoteAddr; X-Forwarded-For só vale atrás de proxy confiável
+func clientIP(r *http.Request) string {
+	if trustProxy() {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			first, _, _ := strings.Cut(fwd, ",")
+			return strings.TrimSpace(first)
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func writeRateLimited(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "5")
+	http.Error(w, "Muitas requisições, tente novamente em instantes", http.StatusTooManyRequests)
+}
+
+// Metrics expõe os contadores de rate limit no formato Prometheus: GET /metrics
+// O scraper se autentica com "Authorization: Bearer $METRICS_TOKEN"; sem a variável, a rota fica desligada.
+func Metrics(w http.ResponseWriter, r *http.Request) {
+	token := os.Getenv("METRICS_TOKEN")
+	if token == "" {
+		http.NotFound(w, r)
+		return
+	}
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+		http.Error(w, "Não autenticado", http.StatusUnauthorized)
+		return
+	}
+
+	limits.load()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	ratelimit.WriteMetrics(w)
+	fmt.Fprintln(w, "# HELP klens_translate_dropped_total Falas descartadas com o tradutor sobrecarregado.")
+	fmt.Fprintln(w, "# TYPE klens_translate_dropped_total counter")
+	fmt.Fprintf(w, "klens_translate_dropped_total %d\n", translateDropped.Load())
+}
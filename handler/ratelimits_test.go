@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricsRequiresToken(t *testing.T) {
+	get := func(auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		SecurityMiddleware(http.HandlerFunc(Metrics)).ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Setenv("METRICS_TOKEN", "")
+	if rec := get("Bearer qualquer"); rec.Code != http.StatusNotFound {
+		t.Fatalf("sem METRICS_TOKEN a rota deveria ficar desligada: %d", rec.Code)
+	}
+
+	t.Setenv("METRICS_TOKEN", "scrape-123")
+	for _, auth := range []string{"", "Bearer errado", "scrape-123"} {
+		if rec := get(auth); rec.Code != http.StatusUnauthorized {
+			t.Fatalf("Authorization %q: status %d", auth, rec.Code)
+		}
+	}
+	rec := get("Bearer scrape-123")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "klens_translate_dropped_total") {
+		t.Fatalf("scraper autenticado: %d %s", rec.Code, rec.Body.String())
+	}
+}
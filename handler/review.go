@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"k-lens/db"
+	"k-lens/hub"
+	"k-lens/models"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxReviewDelay limita a retenção para a live não ficar longe demais do ao vivo
+const maxReviewDelay = 120 * time.Second
+
+// Resultado da revisão de uma legenda retida
+const (
+	reviewApproved   = "approved"
+	reviewEdited     = "edited"
+	reviewSuppressed = "suppressed"
+	reviewReleased   = "released" // Timer venceu sem ação do moderador
+)
+
+// pendingCaption é uma fala já traduzida esperando a hora de ir ao ar
+type pendingCaption struct {
+	LiveID    string
+	CaptionID string
+	StartMs   int64
+	Speaker   string        // Locutor dado pelo tradutor
+	Msgs      []hub.Message // caption_final por idioma
+	Texts     []string      // Texto do tradutor, na mesma ordem de Msgs
+	ReleaseAt time.Time
+
+	// Correções feitas durante a retenção
+	edits    map[string]string // idioma -> texto do moderador
+	speaker  *string
+	editorID uint
+
+	// onPublished roda depois da publicação (contexto, gatilhos de corte)
+	onPublished func(texts []string, speaker string)
+	timer       *time.Timer
+}
+
+// reviewQueue segura as legendas das lives com revisão (LiveArchive.ReviewDelay > 0)
+type reviewQueue struct {
+	mu      sync.Mutex
+	pending map[string]*pendingCaption // caption_id -> legenda
+}
+
+var reviews = &reviewQueue{pending: make(map[string]*pendingCaption)}
+
+// Submit publica na hora ou, com revisão ligada, retém e avisa os moderadores
+func (q *reviewQueue) Submit(h *hub.Hub, p *pendingCaption, delay time.Duration) {
+	if delay <= 0 {
+		q.publish(h, p)
+		return
+	}
+
+	p.ReleaseAt = time.Now().Add(delay)
+	q.mu.Lock()
+	q.pending[p.CaptionID] = p
+	p.timer = time.AfterFunc(delay, func() { q.resolve(h, p.LiveID, p.CaptionID, reviewReleased) })
+	q.mu.Unlock()
+
+	h.Broadcast <- p.pendingMessage()
+}
+
+// take tira a legenda da fila (só quem tira primeiro decide o destino dela)
+func (q *reviewQueue) take(liveID, captionID string) *pendingCaption {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	p, ok := q.pending[captionID]
+	if !ok || p.LiveID != liveID {
+		return nil
+	}
+	delete(q.pending, captionID)
+	p.timer.Stop()
+	return p
+}
+
+// resolve aprova, libera ou suprime a legenda. Retorna false se ela já não estava retida.
+func (q *reviewQueue) resolve(h *hub.Hub, liveID, captionID, status string) bool {
+	p := q.take(liveID, captionID)
+	if p == nil {
+		return false
+	}
+	q.finish(h, p, status)
+	return true
+}
+
+func (q *reviewQueue) finish(h *hub.Hub, p *pendingCaption, status string) {
+	if status != reviewSuppressed {
+		q.publish(h, p)
+	}
+	log.Printf("🛡️ [Revisão] Legenda %s da live %s: %s", p.CaptionID, p.LiveID, status)
+	h.Broadcast <- hub.Message{
+		Type: "caption_review", Payload: map[string]interface{}{"status": status},
+		LiveID: p.LiveID, CaptionID: p.CaptionID, Audience: hub.AudienceStaff,
+	}
+}
+
+// correct aplica uma edição/retirada de moderador numa legenda ainda retida.
+// Retorna false se a legenda não está na fila (já foi ao ar).
+func (q *reviewQueue) correct(h *hub.Hub, c captionCorrection) bool {
+	liveID := strconv.Itoa(int(c.LiveID))
+
+	// Retirar um idioma só: a fala segue na fila com os demais
+	if c.Text == nil && c.Language != "" {
+		q.mu.Lock()
+		p, ok := q.pending[c.CaptionID]
+		if !ok || p.LiveID != liveID {
+			q.mu.Unlock()
+			return false
+		}
+		if len(p.Msgs) > 1 {
+			p.drop(c.Language)
+			msg := p.pendingMessage()
+			q.mu.Unlock()
+			h.Broadcast <- msg
+			return true
+		}
+		q.mu.Unlock()
+	}
+
+	p := q.take(liveID, c.CaptionID)
+	if p == nil {
+		return false
+	}
+	if c.Text == nil {
+		q.finish(h, p, reviewSuppressed)
+		return true
+	}
+
+	if p.edits == nil {
+		p.edits = map[string]string{}
+	}
+	p.edits[c.Language] = *c.Text
+	if c.Speaker != nil {
+		p.speaker = c.Speaker
+	}
+	p.editorID = c.AuthorID
+	q.finish(h, p, reviewEdited)
+	return true
+}
+
+// drop tira um idioma da fala retida
+func (p *pendingCaption) drop(lang string) {
+	for i, msg := range p.Msgs {
+		if msg.Language == lang {
+			p.Msgs = append(p.Msgs[:i:i], p.Msgs[i+1:]...)
+			p.Texts = append(p.Texts[:i:i], p.Texts[i+1:]...)
+			return
+		}
+	}
+}
+
+// flush libera tudo o que estava retido da live (ex: live encerrada)
+func (q *reviewQueue) flush(h *hub.Hub, liveID string) {
+	q.mu.Lock()
+	var ids []string
+	for id, p := range q.pending {
+		if p.LiveID == liveID {
+			ids = append(ids, id)
+		}
+	}
+	q.mu.Unlock()
+	for _, id := range ids {
+		q.resolve(h, liveID, id, reviewReleased)
+	}
+}
+
+// Pending lista as legendas retidas da live, para moderadores que acabaram de entrar
+func (q *reviewQueue) Pending(liveID string) []hub.Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var out []hub.Message
+	for _, p := range q.pending {
+		if p.LiveID == liveID {
+			out = append(out, p.pendingMessage())
+		}
+	}
+	return out
+}
+
+// pendingMessage mostra aos moderadores todos os idiomas da fala e quando ela sai sozinha
+func (p *pendingCaption) pendingMessage() hub.Message {
+	texts := map[string]string{}
+	for i, msg := range p.Msgs {
+		texts[msg.Language] = p.Texts[i]
+	}
+	return hub.Message{
+		Type: "caption_pending",
+		Payload: map[string]interface{}{
+			"texts":      texts,
+			"speaker":    p.Speaker,
+			"release_at": p.ReleaseAt.UnixMilli(),
+		},
+		LiveID: p.LiveID, StartMs: p.StartMs, CaptionID: p.CaptionID, Audience: hub.AudienceStaff,
+	}
+}
+
+// publish leva a legenda ao ar (já com as correções) e grava o original no CaptionLog.
+// Correções feitas na retenção viram a primeira CaptionRevision, como qualquer outra.
+func (q *reviewQueue) publish(h *hub.Hub, p *pendingCaption) {
+	speaker := p.Speaker
+	if p.speaker != nil {
+		speaker = *p.speaker
+	}
+
+	final := make([]string, len(p.Msgs))
+	msgs := make([]hub.Message, len(p.Msgs))
+	for i, msg := range p.Msgs {
+		final[i] = p.Texts[i]
+		if text, ok := p.edits[msg.Language]; ok {
+			final[i] = text
+		}
+		msg.Payload = models.WithSpeaker(speaker, final[i])
+		msg.Speaker = speaker
+		msgs[i] = msg
+	}
+
+	// VIP recebe na hora; o feed gratuito é atrasado e reduzido
+	vipOnly := tiers.publishCaption(h, msgs)
+
+	// Só a versão final vai para o arquivo da live
+	if db.DB != nil {
+		liveID, _ := strconv.ParseUint(p.LiveID, 10, 32)
+		for i, msg := range msgs {
+			row := models.CaptionLog{
+				CaptionID:     p.CaptionID,
+				LiveArchiveID: uint(liveID),
+				Timestamp:     p.StartMs,
+				Text:          p.Texts[i],
+				Language:      msg.Language,
+				Speaker:       p.Speaker,
+				IsVipOnly:     vipOnly,
+			}
+			if err := db.DB.Create(&row).Error; err != nil {
+				log.Printf("⚠️ [Legendas] Erro ao gravar legenda %s: %v", p.CaptionID, err)
+				continue
+			}
+			if final[i] != p.Texts[i] || speaker != p.Speaker {
+				db.DB.Create(&models.CaptionRevision{
+					CaptionLogID: row.ID, Revision: 1, CaptionID: p.CaptionID, LiveArchiveID: row.LiveArchiveID,
+					Language: row.Language, Text: final[i], Speaker: speaker, AuthorID: p.editorID,
+				})
+			}
+		}
+	}
+
+	if p.onPublished != nil {
+		p.onPublished(final, speaker)
+	}
+}
+
+// ApproveCaption libera uma legenda retida: POST /api/lives/{id}/captions/{caption}/approve
+func ApproveCaption(h *hub.Hub, w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireLiveAction(w, r, id, actionEdit); !ok {
+		return
+	}
+	if !reviews.resolve(h, strconv.Itoa(int(id)), mux.Vars(r)["caption"], reviewApproved) {
+		http.Error(w, "Legenda não está aguardando revisão", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": reviewApproved})
+}
+
+// ListPendingCaptions mostra a fila de revisão: GET /api/lives/{id}/review
+func ListPendingCaptions(w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireLiveAction(w, r, id, actionEdit); !ok {
+		return
+	}
+	pending := reviews.Pending(strconv.Itoa(int(id)))
+	if pending == nil {
+		pending = []hub.Message{}
+	}
+	writeJSON(w, http.StatusOK, pending)
+}
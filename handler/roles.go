@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"errors"
+	"k-lens/db"
+	"k-lens/models"
+	"net/http"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Ações controladas por papel
+const (
+	actionAudio  = "audio"  // Enviar áudio para tradução
+	actionConfig = "config" // update_config / audio_format
+	actionClip   = "clip"   // Pedir cortes
+	actionEdit   = "edit"   // Corrigir legendas
+	actionManage = "manage" // Iniciar/encerrar live, gerenciar papéis
+)
+
+var rolePermissions = map[string]map[string]bool{
+	models.RoleHost:      {actionAudio: true, actionConfig: true, actionClip: true, actionEdit: true, actionManage: true},
+	models.RoleModerator: {actionClip: true, actionEdit: true},
+	models.RoleViewer:    {},
+}
+
+func can(role, action string) bool {
+	return rolePermissions[role][action]
+}
+
+// liveRole retorna o papel do usuário na live (viewer se não houver vínculo)
+func liveRole(liveID, userID uint) string {
+	if db.DB == nil {
+		return models.RoleViewer
+	}
+	var member models.LiveMember
+	err := db.DB.Where("live_archive_id = ? AND user_id = ?", liveID, userID).First(&member).Error
+	if err != nil {
+		return models.RoleViewer
+	}
+	return member.Role
+}
+
+// setLiveRole cria ou atualiza o vínculo do usuário com a live
+func setLiveRole(liveID, userID uint, role string) error {
+	member := models.LiveMember{LiveArchiveID: liveID, UserID: userID, Role: role}
+	return db.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "live_archive_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(&member).Error
+}
+
+// requireLiveAction autentica a requisição REST (sessão ou token) e confere a permissão na live.
+// O usuário é nil quando o acesso veio de um token de convite.
+func requireLiveAction(w http.ResponseWriter, r *http.Request, liveID uint, action string) (*models.User, bool) {
+	user, role, err := liveAccess(r, liveID)
+	if err != nil {
+		status, msg := accessStatus(err)
+		http.Error(w, msg, status)
+		return nil, false
+	}
+	if !can(role, action) {
+		http.Error(w, "Permissão negada", http.StatusForbidden)
+		return nil, false
+	}
+	return user, true
+}
+
+// ListLiveMembers lista os papéis da live: GET /api/lives/{id}/members
+func ListLiveMembers(w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireLiveAction(w, r, id, actionManage); !ok {
+		return
+	}
+
+	var members []models.LiveMember
+	if err := db.DB.Where("live_archive_id = ?", id).Order("id").Find(&members).Error; err != nil {
+		http.Error(w, "Erro ao listar membros", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, members)
+}
+
+// SetLiveMember define o papel de um usuário: PUT /api/lives/{id}/members
+// Body: {"email": "...", "role": "moderator"} (ou "user_id")
+func SetLiveMember(w http.ResponseWriter, r *http.Request) {
+	id, err := parseLiveID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := requireLiveAction(w, r, id, actionManage); !ok {
+		return
+	}
+
+	var req struct {
+		UserID uint   `json:"user_id"`
+		Email  string `json:"email"`
+		Role   string `json:"role"`
+	}
+	if err := decodeJSON(w, r, &req); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	if _, ok := rolePermissions[req.Role]; !ok {
+		http.Error(w, "Papel inválido (host, moderator ou viewer)", http.StatusBadRequest)
+		return
+	}
+
+	var user models.User
+	query := db.DB.Where("id = ?", req.UserID)
+	if req.Email != "" {
+		query = db.DB.Where("email = ?", req.Email)
+	}
+	if err := query.First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Usuário não encontrado (precisa ter feito login uma vez)", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Erro ao buscar usuário", http.StatusInternalServerError)
+		return
+	}
+
+	if err := setLiveRole(id, user.ID, req.Role); err != nil {
+		http.Error(w, "Erro ao salvar papel", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"user_id": user.ID, "role": req.Role})
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"k-lens/audio"
+)
+
+// Utterance é um trecho contínuo de fala, pronto para uma única tradução
+type Utterance struct {
+	PCM     []byte
+	StartMs int64 // Milissegundos desde o início da live
+	EndMs   int64
+}
+
+// Segmenter acumula PCM de uma conexão e corta o fluxo em falas completas,
+// usando o VAD do AudioProcessor (RMS + ZCR) com tempo de hangover.
+type Segmenter struct {
+	processor *AudioProcessor
+	format    audio.Format
+
+	HangoverMs     int64 // Silêncio necessário para encerrar a fala
+	MinSpeechMs    int64 // Falas mais curtas que isso são descartadas (estalos, tosse)
+	MaxUtteranceMs int64 // Corte forçado para não segurar legenda demais
+	PreRollMs      int64 // Silêncio mantido antes da fala para não comer a primeira sílaba
+
+	speaking  bool
+	buf       []byte
+	startMs   int64
+	silenceMs int64
+	preRoll   [][]byte
+}
+
+func NewSegmenter(processor *AudioProcessor, format audio.Format) *Segmenter {
+	return &Segmenter{
+		processor:      processor,
+		format:         format,
+		HangoverMs:     700,
+		MinSpeechMs:    400,
+		MaxUtteranceMs: 15000,
+		PreRollMs:      300,
+	}
+}
+
+// Push recebe um frame PCM que chegou em offsetMs (fim do frame, relativo ao início da live)
+// e devolve as falas que se completaram com ele.
+func (s *Segmenter) Push(frame []byte, offsetMs int64) []Utterance {
+	frameMs := s.durationMs(len(frame))
+	if frameMs == 0 {
+		return nil
+	}
+	voiced := s.processor.ShouldProcess(frame)
+
+	if !s.speaking {
+		if !voiced {
+			s.keepPreRoll(frame)
+			return nil
+		}
+		s.speaking = true
+		s.silenceMs = 0
+		s.buf = s.buf[:0]
+		preRollMs := int64(0)
+		for _, f := range s.preRoll {
+			s.buf = append(s.buf, f...)
+			preRollMs += s.durationMs(len(f))
+		}
+		s.preRoll = nil
+		s.startMs = offsetMs - frameMs - preRollMs
+		if s.startMs < 0 {
+			s.startMs = 0
+		}
+	}
+
+	s.buf = append(s.buf, frame...)
+	if voiced {
+		s.silenceMs = 0
+	} else {
+		s.silenceMs += frameMs
+	}
+
+	var done []Utterance
+	switch {
+	case s.silenceMs >= s.HangoverMs:
+		if u, ok := s.cut(); ok {
+			done = append(done, u)
+		}
+		s.speaking = false
+	case s.durationMs(len(s.buf)) >= s.MaxUtteranceMs:
+		// Fala longa demais: entrega o que tem e continua ouvindo a partir daqui
+		if u, ok := s.cut(); ok {
+			done = append(done, u)
+		}
+		s.startMs = offsetMs
+		s.silenceMs = 0
+	}
+	return done
+}
+
+// Flush encerra a fala em andamento (ex: socket fechado ou formato trocado)
+func (s *Segmenter) Flush() (Utterance, bool) {
+	if !s.speaking {
+		return Utterance{}, false
+	}
+	s.speaking = false
+	return s.cut()
+}
+
+// cut fecha o buffer atual como uma Utterance, sem o silêncio do hangover no final
+func (s *Segmenter) cut() (Utterance, bool) {
+	trailing := s.bytesFor(s.silenceMs)
+	if trailing > len(s.buf) {
+		trailing = len(s.buf)
+	}
+	pcm := make([]byte, len(s.buf)-trailing)
+	copy(pcm, s.buf[:len(pcm)])
+	s.buf = s.buf[:0]
+
+	durMs := s.durationMs(len(pcm))
+	if durMs < s.MinSpeechMs {
+		return Utterance{}, false
+	}
+	return Utterance{PCM: pcm, StartMs: s.startMs, EndMs: s.startMs + durMs}, true
+}
+
+func (s *Segmenter) keepPreRoll(frame []byte) {
+	f := make([]byte, len(frame))
+	copy(f, frame)
+	s.preRoll = append(s.preRoll, f)
+
+	total := int64(0)
+	for _, p := range s.preRoll {
+		total += s.durationMs(len(p))
+	}
+	for len(s.preRoll) > 1 && total-s.durationMs(len(s.preRoll[0])) >= s.PreRollMs {
+		total -= s.durationMs(len(s.preRoll[0]))
+		s.preRoll = s.preRoll[1:]
+	}
+}
+
+// durationMs converte bytes PCM16 em milissegundos no formato negociado
+func (s *Segmenter) durationMs(n int) int64 {
+	bytesPerSec := int64(s.format.SampleRate * s.format.Channels * 2)
+	if bytesPerSec == 0 {
+		return 0
+	}
+	return int64(n) * 1000 / bytesPerSec
+}
+
+func (s *Segmenter) bytesFor(ms int64) int {
+	n := int(ms * int64(s.format.SampleRate*s.format.Channels*2) / 1000)
+	return n - n%(2*s.format.Channels)
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"k-lens/audio"
+	"math"
+	"testing"
+)
+
+// frameMs é a duração de cada frame sintético (16 kHz mono PCM16)
+const frameMs = 100
+
+func voicedFrame() []byte {
+	n := 16000 * frameMs / 1000
+	pcm := make([]byte, n*2)
+	for i := 0; i < n; i++ {
+		// Senoide de 200 Hz: volume alto e ZCR baixo, como voz
+		v := int16(8000 * math.Sin(2*math.Pi*200*float64(i)/16000))
+		pcm[2*i], pcm[2*i+1] = byte(v), byte(v>>8)
+	}
+	return pcm
+}
+
+func silentFrame() []byte { return make([]byte, 16000*frameMs/1000*2) }
+
+// feeder empurra frames no segmenter com o relógio da live andando junto
+type feeder struct {
+	seg    *Segmenter
+	now    int64
+	output []Utterance
+}
+
+func newFeeder() *feeder {
+	return &feeder{seg: NewSegmenter(NewAudioProcessor(500), audio.DefaultFormat())}
+}
+
+func (f *feeder) push(frame []byte, count int) {
+	for i := 0; i < count; i++ {
+		f.now += frameMs
+		f.output = append(f.output, f.seg.Push(frame, f.now)...)
+	}
+}
+
+func TestSegmenterCutsAfterHangoverWithPreRoll(t *testing.T) {
+	f := newFeeder()
+	f.push(silentFrame(), 5)
+	f.push(voicedFrame(), 10)
+	f.push(silentFrame(), 6)
+	if len(f.output) != 0 {
+		t.Fatalf("cortou antes do hangover: %d falas", len(f.output))
+	}
+	f.push(silentFrame(), 1)
+	if len(f.output) != 1 {
+		t.Fatalf("esperava 1 fala depois de 700ms de silêncio, veio %d", len(f.output))
+	}
+
+	// 300ms de pre-roll + 1s de voz; o silêncio do hangover fica de fora
+	u := f.output[0]
+	if u.StartMs != 200 || u.EndMs != 1500 {
+		t.Fatalf("fala em %d-%d, esperava 200-1500", u.StartMs, u.EndMs)
+	}
+	if len(u.PCM) != 13*len(silentFrame()) {
+		t.Fatalf("PCM com %d bytes, esperava %d", len(u.PCM), 13*len(silentFrame()))
+	}
+}
+
+func TestSegmenterDiscardsShortSpeech(t *testing.T) {
+	f := newFeeder()
+	f.push(voicedFrame(), 3) // 300ms < MinSpeechMs
+	f.push(silentFrame(), 7)
+	if len(f.output) != 0 {
+		t.Fatalf("estalo curto deveria ser descartado: %+v", f.output)
+	}
+
+	f.push(voicedFrame(), 5)
+	f.push(silentFrame(), 7)
+	if len(f.output) != 1 {
+		t.Fatalf("fala de 500ms deveria passar, vieram %d", len(f.output))
+	}
+}
+
+func TestSegmenterForcesCutAtMaxUtterance(t *testing.T) {
+	f := newFeeder()
+	f.seg.MaxUtteranceMs = 2000
+	f.push(voicedFrame(), 25)
+	if len(f.output) != 1 {
+		t.Fatalf("esperava o corte forçado em 2s, vieram %d falas", len(f.output))
+	}
+	if u := f.output[0]; u.StartMs != 0 || u.EndMs != 2000 {
+		t.Fatalf("primeiro trecho em %d-%d, esperava 0-2000", u.StartMs, u.EndMs)
+	}
+
+	// A fala continua: o resto sai no Flush, a partir do ponto do corte
+	u, ok := f.seg.Flush()
+	if !ok || u.StartMs != 2000 || u.EndMs != 2500 {
+		t.Fatalf("resto da fala: %v %d-%d, esperava 2000-2500", ok, u.StartMs, u.EndMs)
+	}
+}
+
+func TestSegmenterFlush(t *testing.T) {
+	f := newFeeder()
+	if _, ok := f.seg.Flush(); ok {
+		t.Fatal("sem fala em andamento o Flush não entrega nada")
+	}
+
+	f.push(voicedFrame(), 6)
+	f.push(silentFrame(), 2)
+	u, ok := f.seg.Flush()
+	if !ok || u.StartMs != 0 || u.EndMs != 600 {
+		t.Fatalf("flush: %v %d-%d, esperava 0-600 sem o silêncio final", ok, u.StartMs, u.EndMs)
+	}
+	if _, ok := f.seg.Flush(); ok {
+		t.Fatal("a fala já foi entregue")
+	}
+}
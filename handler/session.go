@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"k-lens/db"
+	"k-lens/models"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sessionCookie = "klens_session"
+	sessionTTL    = 30 * 24 * time.Hour
+)
+
+var errNoSession = errors.New("sessão ausente ou expirada")
+
+var (
+	secretOnce sync.Once
+	secretKey  []byte
+)
+
+// signingSecret usa SESSION_SECRET; sem ela, gera uma chave aleatória (cookies não sobrevivem a restart)
+func signingSecret() []byte {
+	secretOnce.Do(func() {
+		if s := os.Getenv("SESSION_SECRET"); s != "" {
+			secretKey = []byte(s)
+			return
+		}
+		log.Println("⚠️ [Sessão] SESSION_SECRET não definida: usando chave temporária")
+		secretKey = make([]byte, 32)
+		rand.Read(secretKey)
+	})
+	return secretKey
+}
+
+// signValue assina o valor com HMAC-SHA256: "<valor>.<assinatura>"
+func signValue(value string) string {
+	mac := hmac.New(sha256.New, signingSecret())
+	mac.Write([]byte(value))
+	return value + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifyValue confere a assinatura e devolve o valor original
+func verifyValue(signed string) (string, bool) {
+	i := strings.LastIndex(signed, ".")
+	if i < 0 {
+		return "", false
+	}
+	value := signed[:i]
+	if !hmac.Equal([]byte(signValue(value)), []byte(signed)) {
+		return "", false
+	}
+	return value, true
+}
+
+func randomToken(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// createSession grava a sessão no banco e entrega o cookie ao navegador
+func createSession(w http.ResponseWriter, r *http.Request, userID uint) error {
+	token := randomToken(32)
+	session := models.Session{
+		TokenHash: hashToken(token),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(sessionTTL),
+	}
+	if err := db.DB.Create(&session).Error; err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// CurrentUser resolve o usuário logado a partir do cookie de sessão
+func CurrentUser(r *http.Request) (*models.User, error) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil || cookie.Value == "" || db.DB == nil {
+		return nil, errNoSession
+	}
+
+	var session models.Session
+	err = db.DB.Where("token_hash = ? AND expires_at > ?", hashToken(cookie.Value), time.Now()).First(&session).Error
+	if err != nil {
+		return nil, errNoSession
+	}
+
+	var user models.User
+	if err := db.DB.First(&user, session.UserID).Error; err != nil {
+		return nil, errNoSession
+	}
+	return &user, nil
+}
+
+// HandleLogout apaga a sessão do banco e o cookie. Só POST: um GET permitiria
+// que qualquer site derrubasse a sessão com um <img src="/auth/logout">.
+func HandleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil && db.DB != nil {
+		db.DB.Where("token_hash = ?", hashToken(cookie.Value)).Delete(&models.Session{})
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1, HttpOnly: true})
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// HandleMe devolve o usuário logado: GET /api/me
+func HandleMe(w http.ResponseWriter, r *http.Request) {
+	user, err := CurrentUser(r)
+	if err != nil {
+		http.Error(w, "Não autenticado", http.StatusUnauthorized)
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
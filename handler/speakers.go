@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"k-lens/audio"
+	"k-lens/speaker"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// voiceRegistry agrupa as vozes de cada live localmente (SPEAKER_CLUSTERING=true).
+// O grupo aprende o nome do membro pelas atribuições do tradutor e vira palpite
+// (e reserva, quando o modelo não souber dizer) para as próximas falas.
+type voiceRegistry struct {
+	once      sync.Once
+	enabled   bool
+	threshold float64
+
+	mu     sync.Mutex
+	byLive map[string]*speaker.Clusterer
+}
+
+var voices = &voiceRegistry{byLive: make(map[string]*speaker.Clusterer)}
+
+// maxVoiceClusters limita os grupos por live (elenco + convidados + ruído)
+const maxVoiceClusters = 12
+
+func (v *voiceRegistry) load() {
+	v.once.Do(func() {
+		v.enabled = os.Getenv("SPEAKER_CLUSTERING") == "true"
+		v.threshold = 0.85
+		if s := os.Getenv("SPEAKER_THRESHOLD"); s != "" {
+			if t, err := strconv.ParseFloat(s, 64); err == nil && t > 0 && t < 1 {
+				v.threshold = t
+			} else {
+				log.Printf("⚠️ [Vozes] SPEAKER_THRESHOLD inválido (%s), usando %.2f", s, v.threshold)
+			}
+		}
+	})
+}
+
+func (v *voiceRegistry) clusterer(liveID string) *speaker.Clusterer {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	c, ok := v.byLive[liveID]
+	if !ok {
+		c = speaker.NewClusterer(v.threshold, maxVoiceClusters)
+		v.byLive[liveID] = c
+	}
+	return c
+}
+
+// Assign calcula o embedding da fala (PCM) e devolve o grupo de voz (-1 se desligado ou sem voz)
+func (v *voiceRegistry) Assign(liveID string, pcm []byte, f audio.Format) int {
+	v.load()
+	if !v.enabled || !f.IsPCM() {
+		return -1
+	}
+	return v.clusterer(liveID).Assign(speaker.Compute(pcm, f.SampleRate, f.Channels))
+}
+
+// Hint é o nome já aprendido para o grupo
+func (v *voiceRegistry) Hint(liveID string, voice int) string {
+	if voice < 0 {
+		return ""
+	}
+	return v.clusterer(liveID).Label(voice)
+}
+
+// Vote ensina ao grupo o nome dado pelo tradutor
+func (v *voiceRegistry) Vote(liveID string, voice int, name string) {
+	if voice < 0 || name == "" {
+		return
+	}
+	v.clusterer(liveID).Vote(voice, name)
+}
+
+// forget descarta os grupos quando a live termina
+func (v *voiceRegistry) forget(liveID string) {
+	v.mu.Lock()
+	delete(v.byLive, liveID)
+	v.mu.Unlock()
+}
+
+// resolveSpeaker ajusta o nome devolvido pelo modelo à grafia do elenco.
+// Com elenco definido, nomes fora dele são descartados.
+func resolveSpeaker(roster []string, name string) string {
+	name = strings.Trim(strings.TrimSpace(name), "[]")
+	if name == "" {
+		return ""
+	}
+	if len(roster) == 0 {
+		if len([]rune(name)) > maxMemberName {
+			return ""
+		}
+		return name
+	}
+	for _, member := range roster {
+		if strings.EqualFold(member, name) {
+			return member
+		}
+	}
+	return ""
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"k-lens/hub"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// freeTier controla o feed gratuito: legendas atrasadas (FREE_CAPTION_DELAY, padrão 15s)
+// e amostradas, no máximo uma a cada FREE_CAPTION_INTERVAL (padrão 3s; "0" desliga).
+// O que fica de fora do feed gratuito é gravado como IsVipOnly.
+type freeTier struct {
+	once     sync.Once
+	delay    time.Duration
+	interval time.Duration
+
+	mu       sync.Mutex
+	lastFree map[string]time.Time // Última legenda entregue ao feed gratuito, por live
+}
+
+var tiers = &freeTier{lastFree: make(map[string]time.Time)}
+
+func (t *freeTier) load() {
+	t.once.Do(func() {
+		t.delay = envDuration("FREE_CAPTION_DELAY", 15*time.Second)
+		t.interval = envDuration("FREE_CAPTION_INTERVAL", 3*time.Second)
+	})
+}
+
+// publishCaption entrega a legenda (uma mensagem por idioma da mesma fala) em tempo real
+// para o feed VIP e, se couber no feed reduzido, agenda a entrega atrasada para os gratuitos.
+// A amostragem vale para a fala inteira. Retorna true se ficou só para VIPs.
+func (t *freeTier) publishCaption(h *hub.Hub, msgs []hub.Message) bool {
+	t.load()
+	if len(msgs) == 0 {
+		return true
+	}
+
+	for _, msg := range msgs {
+		vip := msg
+		vip.Audience = hub.AudienceVIP
+		h.Broadcast <- vip
+	}
+
+	liveID := msgs[0].LiveID
+	t.mu.Lock()
+	now := time.Now()
+	if t.interval > 0 && now.Sub(t.lastFree[liveID]) < t.interval {
+		t.mu.Unlock()
+		return true
+	}
+	t.lastFree[liveID] = now
+	t.mu.Unlock()
+
+	time.AfterFunc(t.delay, func() {
+		for _, msg := range msgs {
+			free := msg
+			free.Audience = hub.AudienceFree
+			h.Broadcast <- free
+		}
+	})
+	return false
+}
+
+// publishCorrection entrega caption_update/caption_delete: VIP na hora e o feed
+// gratuito com o mesmo atraso da legenda, para a correção não chegar antes dela.
+// Legenda IsVipOnly não foi ao feed gratuito, então a correção também não vai.
+func (t *freeTier) publishCorrection(h *hub.Hub, msg hub.Message, vipOnly bool) {
+	t.load()
+
+	vip := msg
+	vip.Audience = hub.AudienceVIP
+	h.Broadcast <- vip
+	if vipOnly {
+		return
+	}
+
+	free := msg
+	free.Audience = hub.AudienceFree
+	time.AfterFunc(t.delay, func() { h.Broadcast <- free })
+}
+
+// publishTranscript entrega a transcrição completa (coreano original) da fala só para
+// o feed VIP, sem idioma: vale para todos os idiomas de legenda
+func (t *freeTier) publishTranscript(h *hub.Hub, msg hub.Message) {
+	if msg.Payload == "" {
+		return
+	}
+	msg.Audience = hub.AudienceVIP
+	h.Broadcast <- msg
+}
+
+// forget libera o estado do feed gratuito quando a live termina
+func (t *freeTier) forget(liveID string) {
+	t.mu.Lock()
+	delete(t.lastFree, liveID)
+	t.mu.Unlock()
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	if v == "0" {
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("⚠️ [Config] %s inválido (%s), usando %s", name, v, def)
+		return def
+	}
+	return d
+}
+
+// envListsEmail indica se o email está na lista (separada por vírgula) da variável
+func envListsEmail(name, email string) bool {
+	for _, v := range strings.Split(os.Getenv(name), ",") {
+		if v = strings.TrimSpace(v); v != "" && strings.EqualFold(v, email) {
+			return true
+		}
+	}
+	return false
+}
+
+func envInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Printf("⚠️ [Config] %s inválido (%s), usando %d", name, v, def)
+		return def
+	}
+	return n
+}
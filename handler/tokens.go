@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"k-lens/db"
+	"k-lens/models"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const accessCookie = "klens_token"
+
+var errBadToken = errors.New("token inválido, expirado ou revogado")
+
+// AccessClaims é o conteúdo de um token de acesso assinado
+type AccessClaims struct {
+	ID        string `json:"jti"`            // Identificador para revogação
+	Subject   string `json:"sub"`            // Quem recebeu (email ou "invite")
+	Role      string `json:"role"`           // Papel concedido na live
+	LiveID    uint   `json:"live,omitempty"` // Escopo: o token só vale para esta live
+	ExpiresAt int64  `json:"exp"`
+}
+
+// mintToken assina as claims: "<json base64url>.<hmac>"
+func mintToken(c AccessClaims) string {
+	payload, _ := json.Marshal(c)
+	return signValue(base64.RawURLEncoding.EncodeToString(payload))
+}
+
+// parseToken confere assinatura, validade e revogação
+func parseToken(token string) (*AccessClaims, error) {
+	value, ok := verifyValue(token)
+	if !ok {
+		return nil, errBadToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errBadToken
+	}
+	var c AccessClaims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, errBadToken
+	}
+	if time.Now().Unix() > c.ExpiresAt {
+		return nil, errBadToken
+	}
+	if _, ok := rolePermissions[c.Role]; !ok || c.ID == "" {
+		return nil, errBadToken
+	}
+
+	// Todo token emitido tem um registro; revogado ou apagado = inválido
+	if db.DB != nil {
+		var invite models.AccessInvite
+		if err := db.DB.Where("token_id = ?", c.ID).First(&invite).Error; err != nil || invite.RevokedAt != nil {
+			return nil, errBadToken
+		}
+	}
+	return &c, nil
+}
+
+// tokenFromRequest aceita o token no header Authorization, na query ou no cookie.
+// A query vem antes do cookie: um link de convite novo substitui o cookie de um convite antigo.
+// fromCookie indica que o token veio só do cookie guardado pelo navegador.
+func tokenFromRequest(r *http.Request) (token string, fromCookie bool) {
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(h, "Bearer ")), false
+	}
+	if t := r.URL.Query().Get("token"); t != "" {
+		return t, false
+	}
+	if c, err := r.Cookie(accessCookie); err == nil && c.Value != "" {
+		return c.Value, true
+	}
+	return "", false
+}
+
+type claimsKey struct{}
+
+// requestClaims devolve as claims validadas pelo SecurityMiddleware (nil se não houver token)
+func requestClaims(r *http.Request) *AccessClaims {
+	c, _ := r.Context().Value(claimsKey{}).(*AccessClaims)
+	return c
+}
+
+func withClaims(r *http.Request, c *AccessClaims) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), claimsKey{}, c))
+}
+
+// liveAccess resolve quem está acessando a live e com qual papel.
+// Sessão tem prioridade (papel vem do banco); sem sessão, vale o token assinado,
+// desde que o escopo dele seja esta live. user é nil para convidados anônimos.
+func liveAccess(r *http.Request, liveID uint) (user *models.User, role string, err error) {
+	if user, err := CurrentUser(r); err == nil {
+		return user, liveRole(liveID, user.ID), nil
+	}
+	claims := requestClaims(r)
+	if claims == nil {
+		return nil, "", errNoSession
+	}
+	if claims.LiveID != 0 && claims.LiveID != liveID {
+		return nil, "", errTokenScope
+	}
+	return nil, claims.Role, nil
+}
+
+var errTokenScope = errors.New("token não vale para esta live")
+
+// accessStatus traduz o erro de liveAccess para o status HTTP
+func accessStatus(err error) (int, string) {
+	if errors.Is(err, errTokenScope) {
+		return http.StatusForbidden, "Token não vale para esta live"
+	}
+	return http.StatusUnauthorized, "Não autenticado"
+}
+
+// protectedPath indica as rotas que exigem sessão ou token válido
+func protectedPath(path string) bool {
+	for _, prefix := range []string{"/api/", "/ws/", "/recordings/"} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
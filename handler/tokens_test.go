@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"k-lens/db"
+	"k-lens/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// whoAmI responde com o papel das claims (ou "sessão" quando o acesso veio do login)
+var whoAmI = SecurityMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if c := requestClaims(r); c != nil {
+		w.Write([]byte(c.Role))
+		return
+	}
+	w.Write([]byte("sessão"))
+}))
+
+func TestSecurityMiddlewareTokenPrecedence(t *testing.T) {
+	withTestDB(t, &models.User{}, &models.Session{}, &models.AccessInvite{})
+	session := loginAs(t, &models.User{GoogleID: "g-1", Email: "army@klens.test"})
+
+	stale := inviteToken(t, 1, models.RoleViewer)
+	now := time.Now()
+	db.DB.Model(&models.AccessInvite{}).Where("live_archive_id = ?", 1).Update("revoked_at", &now)
+	fresh := inviteToken(t, 2, models.RoleModerator)
+
+	cases := []struct {
+		name        string
+		query       string
+		cookies     []*http.Cookie
+		status      int
+		body        string
+		clearCookie bool
+	}{
+		{"link novo vence o cookie antigo", "?token=" + fresh, []*http.Cookie{{Name: accessCookie, Value: stale}}, http.StatusOK, models.RoleModerator, false},
+		{"cookie revogado com sessão válida", "", []*http.Cookie{{Name: accessCookie, Value: stale}, session}, http.StatusOK, "sessão", true},
+		{"cookie revogado sem sessão", "", []*http.Cookie{{Name: accessCookie, Value: stale}}, http.StatusUnauthorized, "", true},
+		{"link revogado", "?token=" + stale, []*http.Cookie{session}, http.StatusUnauthorized, "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/lives/2"+tc.query, nil)
+			for _, c := range tc.cookies {
+				req.AddCookie(c)
+			}
+			rec := httptest.NewRecorder()
+			whoAmI.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Fatalf("status %d, esperava %d: %s", rec.Code, tc.status, rec.Body.String())
+			}
+			if tc.body != "" && rec.Body.String() != tc.body {
+				t.Fatalf("acesso por %q, esperava %q", rec.Body.String(), tc.body)
+			}
+			cleared := false
+			for _, c := range rec.Result().Cookies() {
+				if c.Name == accessCookie && c.MaxAge < 0 {
+					cleared = true
+				}
+			}
+			if cleared != tc.clearCookie {
+				t.Fatalf("cookie de convite apagado=%v, esperava %v", cleared, tc.clearCookie)
+			}
+		})
+	}
+}
@@ -3,16 +3,20 @@ package handler
 import (
 	"context"
 	"encoding/json"
-	"k-lens/db"
+	"fmt"
+	"k-lens/audio"
+	"k-lens/highlight"
 	"k-lens/hub"
 	"k-lens/media"
 	"k-lens/models"
 	"k-lens/translate"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -43,17 +47,51 @@ func checkOrigin(r *http.Request) bool {
 	return false
 }
 
+// maxReverseText limita o texto da tradução reversa (cada chamada vai ao modelo)
+const maxReverseText = 1000
+
 var (
-	semaphore    = make(chan struct{}, 5)
-	globalGemini *translate.GeminiService
-	videoCutter  = media.NewCutter()
+	semaphore   = make(chan struct{}, 5)
+	videoCutter = media.NewCutter()
 )
 
-func ServeWS(h *hub.Hub, gemini *translate.GeminiService, w http.ResponseWriter, r *http.Request) {
-	globalGemini = gemini
+func ServeWS(h *hub.Hub, translator translate.Translator, w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	liveIDStr := vars["id"]
-	liveID, _ := strconv.ParseUint(liveIDStr, 10, 32)
+	liveID, err := strconv.ParseUint(liveIDStr, 10, 32)
+	if err != nil || liveID == 0 {
+		http.Error(w, "ID de live inválido", http.StatusBadRequest)
+		return
+	}
+	live, err := lives.Get(uint(liveID))
+	if err != nil {
+		writeLiveError(w, err)
+		return
+	}
+	if live.Status == models.LiveEnded {
+		http.Error(w, "Live encerrada", http.StatusGone)
+		return
+	}
+
+	// Toda conexão é de um usuário autenticado (ou convidado por token), com papel definido na live
+	user, role, err := liveAccess(r, uint(liveID))
+	if err != nil {
+		status, msg := accessStatus(err)
+		http.Error(w, msg, status)
+		return
+	}
+	identity, ip := identityKey(r), clientIP(r)
+	if !limits.load().connect.allow(identity, ip) {
+		writeRateLimited(w)
+		return
+	}
+
+	var who string
+	if user != nil {
+		who = user.Email
+	} else {
+		who = "convidado " + requestClaims(r).Subject
+	}
 
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -62,36 +100,94 @@ func ServeWS(h *hub.Hub, gemini *translate.GeminiService, w http.ResponseWriter,
 	}
 
 	processor := NewAudioProcessor(500.0)
-	clientChan := make(chan hub.Message, 256)
-	h.Register <- clientChan
-	startTime := time.Now()
+	format := audio.DefaultFormat()
+	segmenter := NewSegmenter(processor, format)
+	chunks := NewChunkBuffer(format)
+	client := hub.NewClient(liveIDStr)
+	client.Role = role
+	// Equipe da live sempre recebe o feed completo (e a fila de revisão)
+	client.Staff = role != models.RoleViewer
+	client.VIP = client.Staff
+	if user != nil {
+		client.UserID = user.ID
+		client.VIP = client.VIP || user.IsVIP
+	}
+	// Idioma de legenda escolhido na entrada (?lang=en); padrão é o principal da live
+	client.Language = pickLanguage(uint(liveID), r.URL.Query().Get("lang"))
+	if client.Staff {
+		// Moderador que entra no meio da revisão vê o que já está retido (antes do Register,
+		// o cliente ainda não está na sala e o buffer de envio é só dele)
+		for _, msg := range reviews.Pending(liveIDStr) {
+			select {
+			case client.Send <- msg:
+			default:
+			}
+		}
+	}
+	h.Register <- client
+	log.Printf("🔌 [WebSocket] %s entrou na live %s como %s (%s)", who, liveIDStr, role, client.Language)
+
+	// allowed confere a permissão do papel e avisa o cliente quando negar
+	deniedWarned := map[string]bool{}
+	allowed := func(action string) bool {
+		if can(role, action) {
+			return true
+		}
+		if action != actionAudio || !deniedWarned[action] {
+			h.SendTo(client, hub.Message{Type: "error", Payload: "Permissão negada: " + role + " não pode " + action, LiveID: liveIDStr})
+			deniedWarned[action] = true
+		}
+		return false
+	}
+	// Offsets sempre relativos ao início oficial da live (igual para todas as conexões)
+	liveOffset := func(at time.Time) (int64, bool) {
+		return lives.Offset(uint(liveID), at)
+	}
+	notStartedWarned := false
+	// rateLimited avisa o cliente uma vez por rajada (o aviso volta depois de um envio aceito)
+	rateWarned := map[string]bool{}
+	rateLimited := func(guard *rateGuard, what string) bool {
+		if guard.allow(identity, ip) {
+			rateWarned[what] = false
+			return false
+		}
+		if !rateWarned[what] {
+			h.SendTo(client, hub.Message{Type: "error", Payload: "Limite de " + what + " excedido, aguarde", LiveID: liveIDStr})
+			rateWarned[what] = true
+		}
+		return true
+	}
 
-	var currentLiveURL string
+	currentLiveURL := live.SourceURL
 
 	defer func() {
-		h.Unregister <- clientChan
+		h.Unregister <- client
 		conn.Close()
 	}()
 
-	// Goroutine para escutar clipes concluídos e avisar o celular via HUB
-	go func() {
-		for clipName := range videoCutter.NotifyChan {
-			msg := hub.Message{
-				Type:    "CLIP_READY",
-				Payload: "Clipe disponível",
-				Url:     "/recordings/" + clipName, // Agora o campo Url existe no hub.Message
-				LiveID:  liveIDStr,
-			}
-			h.Broadcast <- msg
+	pipeline := &captionPipeline{hub: h, translator: translator, client: client, live: live,
+		sourceURL: func() string { return currentLiveURL }}
+	translateSegment := pipeline.translateSegment
+
+	// dispatchUtterance embrulha a fala em WAV e dispara a tradução
+	dispatchUtterance := func(u Utterance, f audio.Format) {
+		go func() {
+			voice := voices.Assign(liveIDStr, u.PCM, f)
+			translateSegment(audio.EncodeWAV(u.PCM, f.SampleRate, f.Channels), f.MIMEType(), u.StartMs, u.EndMs, voice)
+		}()
+	}
+	defer func() {
+		if u, ok := segmenter.Flush(); ok && translator != nil {
+			dispatchUtterance(u, format)
+		}
+		if c, ok := chunks.Flush(); ok && translator != nil {
+			go translateSegment(c.Audio, c.MIMEType, c.StartMs, c.EndMs, -1)
 		}
 	}()
 
-	// Goroutine de escrita (Servidor -> App)
+	// Goroutine de escrita (Servidor -> App): a sala já entrega só mensagens desta live
 	go func() {
-		for message := range clientChan {
-			if message.LiveID != "" && message.LiveID != liveIDStr {
-				continue
-			}
+		for message := range client.Send {
 			conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
 			if err := conn.WriteJSON(message); err != nil {
 				return
@@ -124,88 +220,296 @@ func ServeWS(h *hub.Hub, gemini *translate.GeminiService, w http.ResponseWriter,
 				continue
 			}
 
-			if raw["action"] == "update_config" {
-				duration, _ := strconv.Atoi(interfaceToString(raw["duration"]))
-				ratio := interfaceToString(raw["ratio"])
-				url := interfaceToString(raw["live_url"])
+			// Negociação do formato de áudio: {"action":"audio_format","encoding":"pcm16","sample_rate":48000,"channels":1}
+			if raw["action"] == "audio_format" {
+				if !allowed(actionConfig) {
+					continue
+				}
+				sampleRate, _ := strconv.Atoi(interfaceToString(raw["sample_rate"]))
+				channels, _ := strconv.Atoi(interfaceToString(raw["channels"]))
+				negotiated, err := audio.Negotiate(interfaceToString(raw["encoding"]), sampleRate, channels)
+				if err != nil {
+					log.Printf("⚠️ [WebSocket] Formato de áudio recusado: %v", err)
+					h.SendTo(client, hub.Message{Type: "error", Payload: err.Error(), LiveID: liveIDStr})
+					continue
+				}
+				if u, ok := segmenter.Flush(); ok && translator != nil {
+					dispatchUtterance(u, format)
+				}
+				if c, ok := chunks.Flush(); ok && translator != nil {
+					go translateSegment(c.Audio, c.MIMEType, c.StartMs, c.EndMs, -1)
+				}
+				format = negotiated
+				segmenter = NewSegmenter(processor, format)
+				chunks = NewChunkBuffer(format)
+				log.Printf("🎙️ [Áudio] Formato negociado: %s %dHz %dch", format.Encoding, format.SampleRate, format.Channels)
+				continue
+			}
+
+			// Troca do idioma de legenda: {"action":"language","lang":"es"} (qualquer papel)
+			if raw["action"] == "language" {
+				lang := pickLanguage(uint(liveID), interfaceToString(raw["lang"]))
+				h.SetLanguage(client, lang)
+				h.SendTo(client, hub.Message{Type: "language", Payload: lang, LiveID: liveIDStr})
+				continue
+			}
+
+			// Reação do público (qualquer papel): {"action":"reaction","emoji":"💜"}
+			// Só alimenta as regras de pico de reações; o emoji não é repassado
+			if raw["action"] == "reaction" {
+				if rateLimited(limits.load().reaction, "reações") {
+					continue
+				}
+				if offsetMs, started := liveOffset(time.Now()); started {
+					highlights.Observe(uint(liveID), currentLiveURL, offsetMs, highlight.Signal{Kind: highlight.SignalReaction, Count: 1})
+				}
+				continue
+			}
+
+			// Aprovação de legenda retida para revisão: {"action":"caption_approve","caption_id":"..."}
+			if raw["action"] == "caption_approve" {
+				if !allowed(actionEdit) {
+					continue
+				}
+				if !reviews.resolve(h, liveIDStr, interfaceToString(raw["caption_id"]), reviewApproved) {
+					h.SendTo(client, hub.Message{Type: "error", Payload: "Legenda não está aguardando revisão", LiveID: liveIDStr})
+				}
+				continue
+			}
+
+			// Correção de legenda por moderador:
+			// {"action":"caption_edit","caption_id":"...","language":"pt-BR","text":"...","speaker":"Jimin"}
+			// {"action":"caption_delete","caption_id":"...","language":""}  (vazio = todos os idiomas)
+			if raw["action"] == "caption_edit" || raw["action"] == "caption_delete" {
+				if !allowed(actionEdit) {
+					continue
+				}
+				correction := captionCorrection{
+					LiveID: uint(liveID), CaptionID: interfaceToString(raw["caption_id"]),
+					Language: interfaceToString(raw["language"]), AuthorID: client.UserID,
+				}
+				if raw["action"] == "caption_edit" {
+					text := interfaceToString(raw["text"])
+					correction.Text = &text
+					if v, ok := raw["speaker"]; ok {
+						speaker := interfaceToString(v)
+						correction.Speaker = &speaker
+					}
+				}
+				if _, err := applyCorrection(h, correction); err != nil {
+					h.SendTo(client, hub.Message{Type: "error", Payload: "Correção recusada: " + err.Error(), LiveID: liveIDStr})
+				}
+				continue
+			}
 
-				videoCutter.UpdateConfig(duration, ratio)
-				currentLiveURL = url
+			if raw["action"] == "update_config" {
+				if !allowed(actionConfig) {
+					continue
+				}
+				if list := interfaceToStrings(raw["languages"]); list != nil {
+					langs, err := lives.SetLanguages(uint(liveID), list)
+					if err != nil {
+						h.SendTo(client, hub.Message{Type: "error", Payload: "Idiomas recusados: " + err.Error(), LiveID: liveIDStr})
+						continue
+					}
+					h.Broadcast <- hub.Message{Type: "live_languages", Payload: langs, LiveID: liveIDStr}
+				}
+				if list := interfaceToStrings(raw["roster"]); list != nil {
+					if _, err := lives.SetRoster(uint(liveID), list); err != nil {
+						h.SendTo(client, hub.Message{Type: "error", Payload: "Elenco recusado: " + err.Error(), LiveID: liveIDStr})
+						continue
+					}
+				}
+				if v, ok := raw["review_delay"].(float64); ok {
+					if _, err := lives.SetReviewDelay(uint(liveID), int(v)); err != nil {
+						h.SendTo(client, hub.Message{Type: "error", Payload: "Revisão recusada: " + err.Error(), LiveID: liveIDStr})
+						continue
+					}
+				}
+				settings, err := clipDefaults.Set(liveIDStr, applyClipOverrides(clipDefaults.Get(liveIDStr), raw))
+				if err != nil {
+					h.SendTo(client, hub.Message{Type: "error", Payload: "Configuração recusada: " + err.Error(), LiveID: liveIDStr})
+					continue
+				}
+				log.Printf("⚙️ [Config] Live %s: %ds em %s", liveIDStr, settings.Duration, settings.AspectRatio)
+				if url := interfaceToString(raw["live_url"]); url != "" {
+					currentLiveURL = url
+					videoCutter.StartRecording(liveIDStr, url)
+				}
 				continue
 			}
 
 			if raw["type"] == "MANUAL_CLIP" {
-				ratio := interfaceToString(raw["ratio"])
+				if !allowed(actionClip) || rateLimited(limits.load().clip, "cortes") {
+					continue
+				}
 				url := interfaceToString(raw["url"])
 				if url == "" {
 					url = currentLiveURL
 				}
 
+				spec := media.ClipSpec{
+					ClipSettings: applyClipOverrides(clipDefaults.Get(liveIDStr), raw),
+					Label:        interfaceToString(raw["label"]),
+				}
+				if spec.Label == "" {
+					spec.Label = "manual_premium"
+				}
+				ratio := spec.AspectRatio
 				log.Printf("🕹️ [MANUAL] Solicitado corte em %s", ratio)
-				videoCutter.UpdateConfig(61, ratio)
 
-				milliOffset := time.Since(startTime).Milliseconds()
-				go videoCutter.CreateClip(liveIDStr, url, float64(milliOffset), "manual_premium")
+				triggeredAt := time.Now()
+				milliOffset, started := liveOffset(triggeredAt)
+				if !started {
+					h.SendTo(client, hub.Message{Type: "error", Payload: "Live ainda não iniciada", LiveID: liveIDStr})
+					continue
+				}
+				if _, err := videoCutter.CreateClip(liveIDStr, url, float64(milliOffset), triggeredAt, spec); err != nil {
+					h.SendTo(client, hub.Message{Type: "error", Payload: "Corte recusado: " + err.Error(), LiveID: liveIDStr})
+					continue
+				}
 
 				h.Broadcast <- hub.Message{
 					Type: "translation", Payload: "🎬 SOLICITANDO CORTE (" + ratio + ")...", LiveID: liveIDStr,
+					Audience: hub.AudienceVIP,
 				}
 				continue
 			}
 		}
 
-		if messageType == websocket.BinaryMessage && gemini != nil {
-			if len(p) < 100 || !processor.ShouldProcess(p) {
+		if messageType == websocket.BinaryMessage && translator != nil {
+			// Áudio só do host; o aviso de negação vai uma vez só para não inundar o socket
+			if !allowed(actionAudio) || rateLimited(limits.load().audio, "áudio") {
+				continue
+			}
+			if len(p) < 100 {
+				continue
+			}
+			offsetMs, started := liveOffset(time.Now())
+			if !started {
+				if !notStartedWarned {
+					h.SendTo(client, hub.Message{Type: "error", Payload: "Live ainda não iniciada: áudio ignorado", LiveID: liveIDStr})
+					notStartedWarned = true
+				}
 				continue
 			}
 
-			go func(audioData []byte) {
-				select {
-				case semaphore <- struct{}{}:
-					defer func() { <-semaphore }()
-				default:
-					return
+			// Containers comprimidos (webm/ogg/flac) não passam pelo VAD: os chunks são
+			// juntados (com o cabeçalho do container na frente) até alguns segundos de áudio
+			if !format.IsPCM() || audio.Sniff(p) != "" || chunks.Streaming() {
+				if c, ok := chunks.Push(p, offsetMs); ok {
+					go translateSegment(c.Audio, c.MIMEType, c.StartMs, c.EndMs, -1)
 				}
+				continue
+			}
 
-				// Timeout aumentado para 30 segundos para melhor robustez
-				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-				defer cancel()
-				log.Printf("⏱️ [Gemini] Processando áudio com timeout de 30s")
+			// PCM cru: volume alimenta as regras de pico e a fala é acumulada até fechar
+			highlights.Observe(uint(liveID), currentLiveURL, offsetMs, highlight.Signal{Kind: highlight.SignalAudio, Loudness: highlight.Loudness(p)})
+			for _, u := range segmenter.Push(p, offsetMs) {
+				dispatchUtterance(u, format)
+			}
+		}
+	}
+}
 
-				resultado, err := gemini.TranslateAudio(ctx, audioData)
-				if err != nil {
-					log.Printf("❌ [Gemini] Erro na tradução de áudio: %v", err)
-					return
-				}
-				if resultado == "" {
-					log.Printf("⚠️ [Gemini] Resposta vazia do Gemini")
-					return
-				}
+// StartClipPipeline sobe os workers de corte (CLIP_WORKERS, padrão 2) e repassa
+// o andamento de cada pedido para a sala da live que pediu.
+func StartClipPipeline(ctx context.Context, h *hub.Hub) {
+	workers, _ := strconv.Atoi(os.Getenv("CLIP_WORKERS"))
+	if workers <= 0 {
+		workers = 2
+	}
+	go forwardClipNotices(h)
+	videoCutter.Start(ctx, workers)
+}
 
-				milliOffset := time.Since(startTime).Milliseconds()
+func forwardClipNotices(h *hub.Hub) {
+	for notice := range videoCutter.NotifyChan {
+		if notice.Status == models.ClipJobDone {
+			h.Broadcast <- hub.Message{
+				Type:    "CLIP_READY",
+				Payload: "Clipe disponível",
+				Url:     "/recordings/" + notice.FileName,
+				LiveID:  notice.LiveID,
+				// Link de download é conteúdo VIP
+				Audience: hub.AudienceVIP,
+			}
+			continue
+		}
+		h.Broadcast <- hub.Message{
+			Type: "clip_status",
+			Payload: map[string]interface{}{
+				"job_id": notice.JobID,
+				"status": notice.Status,
+				"error":  notice.Error,
+			},
+			LiveID:   notice.LiveID,
+			Audience: hub.AudienceVIP,
+		}
+	}
+}
 
-				if db.DB != nil {
-					db.DB.Create(&models.CaptionLog{
-						LiveArchiveID: uint(liveID),
-						Timestamp:     milliOffset,
-						Text:          resultado,
-					})
-				}
+// applyClipOverrides aplica sobre os padrões só os campos enviados na mensagem
+func applyClipOverrides(cs models.ClipSettings, raw map[string]interface{}) models.ClipSettings {
+	if v, err := strconv.Atoi(interfaceToString(raw["duration"])); err == nil && v > 0 {
+		cs.Duration = v
+	}
+	if v, err := strconv.Atoi(interfaceToString(raw["pre_roll"])); err == nil {
+		cs.PreRoll = v
+	}
+	if v := interfaceToString(raw["ratio"]); v != "" {
+		cs.AspectRatio = v
+	}
+	if v := interfaceToString(raw["watermark"]); v != "" {
+		cs.Watermark = v
+	}
+	if v := interfaceToString(raw["quality"]); v != "" {
+		cs.Quality = v
+	}
+	if v := interfaceToString(raw["subtitles"]); v != "" {
+		cs.Subtitles.Mode = v
+	}
+	if v := interfaceToString(raw["sub_font"]); v != "" {
+		cs.Subtitles.Font = v
+	}
+	if v, err := strconv.Atoi(interfaceToString(raw["sub_size"])); err == nil {
+		cs.Subtitles.Size = v
+	}
+	if v := interfaceToString(raw["sub_position"]); v != "" {
+		cs.Subtitles.Position = v
+	}
+	if v := interfaceToString(raw["sub_language"]); v != "" {
+		cs.Subtitles.Language = v
+	}
+	return cs
+}
 
-				lowResult := strings.ToLower(resultado)
-				if strings.Contains(lowResult, "💜") || strings.Contains(lowResult, "tchau") || strings.Contains(lowResult, "obrigado") {
-					if currentLiveURL != "" {
-						log.Printf("🎬 [GATILHO IA] Criando clipe para: %s", resultado)
-						go videoCutter.CreateClip(liveIDStr, currentLiveURL, float64(milliOffset), "highlight")
-					}
-				}
+// interfaceToStrings aceita lista JSON (["pt-BR","en"]) ou texto separado por vírgula
+func interfaceToStrings(v interface{}) []string {
+	switch list := v.(type) {
+	case []interface{}:
+		out := make([]string, 0, len(list))
+		for _, item := range list {
+			out = append(out, interfaceToString(item))
+		}
+		return out
+	case string:
+		return strings.Split(list, ",")
+	}
+	return nil
+}
 
-				h.Broadcast <- hub.Message{
-					Type: "translation", Payload: resultado, LiveID: liveIDStr,
-				}
-			}(p)
+// pickLanguage escolhe o idioma do cliente entre os da live (cai no principal se não houver)
+func pickLanguage(liveID uint, requested string) string {
+	langs := lives.Languages(liveID)
+	if lang, ok := translate.NormalizeLanguage(requested); ok {
+		for _, l := range langs {
+			if l == lang {
+				return lang
+			}
 		}
 	}
+	return langs[0]
 }
 
 func interfaceToString(v interface{}) string {
@@ -224,23 +528,35 @@ func interfaceToString(v interface{}) string {
 	}
 }
 
-func ReverseTranslate(w http.ResponseWriter, r *http.Request) {
-	if globalGemini == nil {
-		http.Error(w, "Gemini não configurado", 500)
+func ReverseTranslate(translator translate.Translator, w http.ResponseWriter, r *http.Request) {
+	if translator == nil {
+		http.Error(w, "Tradutor não configurado", 500)
+		return
+	}
+	if !limits.load().reverse.allow(identityKey(r), clientIP(r)) {
+		writeRateLimited(w)
 		return
 	}
 	var req struct {
 		Text string `json:"text"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeJSON(w, r, &req); err != nil {
 		http.Error(w, "JSON inválido", 400)
 		return
 	}
+	if strings.TrimSpace(req.Text) == "" {
+		http.Error(w, "Texto vazio", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(req.Text) > maxReverseText {
+		http.Error(w, fmt.Sprintf("Texto acima de %d caracteres", maxReverseText), http.StatusRequestEntityTooLarge)
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	coreano, err := globalGemini.TranslateText(ctx, req.Text)
+	coreano, err := translator.TranslateText(ctx, req.Text)
 	if err != nil {
 		coreano = "Erro na tradução"
 	}
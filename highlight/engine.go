@@ -0,0 +1,229 @@
+package highlight
+
+import (
+	"fmt"
+	"k-lens/models"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tipos de sinal observados pelo motor
+const (
+	SignalCaption  = "caption"  // Legenda publicada (tradução + transcrição)
+	SignalAudio    = "audio"    // Trecho de áudio do host
+	SignalReaction = "reaction" // Reações do público
+)
+
+// CombineWindow é quanto tempo o disparo de uma regra fica valendo para somar
+// com outras (ex: grito + "obrigado" logo depois viram um corte só)
+const CombineWindow = 10 * time.Second
+
+// Parâmetros da média de volume usada como referência dos picos
+const (
+	loudnessAlpha  = 0.02 // Peso de cada trecho na média móvel
+	loudnessWarmup = 25   // Trechos antes de começar a comparar
+)
+
+// Signal é algo que aconteceu na live
+type Signal struct {
+	Kind        string
+	At          time.Time
+	Translation string  // SignalCaption: legendas de todos os idiomas
+	Transcript  string  // SignalCaption: coreano original
+	Loudness    float64 // SignalAudio: dBFS do trecho
+	Count       int     // SignalReaction: quantidade de reações
+}
+
+// Hit é uma regra que disparou, com o valor medido
+type Hit struct {
+	Rule  string  `json:"rule"`
+	Kind  string  `json:"kind"`
+	Value float64 `json:"value"`
+}
+
+// Decision é um corte que o motor decidiu (ou decidiria, em dry-run)
+type Decision struct {
+	Score  float64 `json:"score"`
+	Hits   []Hit   `json:"hits"`
+	DryRun bool    `json:"dry_run"`
+}
+
+// Reason resume as regras para o log e para o pedido de corte
+func (d *Decision) Reason() string {
+	parts := make([]string, len(d.Hits))
+	for i, hit := range d.Hits {
+		parts[i] = fmt.Sprintf("%s=%.4g", hit.Rule, hit.Value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+type ruleState struct {
+	rule    models.HighlightRule
+	words   []string
+	re      *regexp.Regexp
+	lastCut time.Time
+	events  []event // caption_rate/reactions: ocorrências dentro da janela
+	hit     *Hit    // Disparo esperando somar com outros
+	hitAt   time.Time
+}
+
+type event struct {
+	at    time.Time
+	count int
+}
+
+// Engine avalia as regras de uma live. Cada regra que dispara (e não está em cooldown)
+// soma seu peso por CombineWindow; quando o placar chega a Threshold, sai uma Decision
+// e todas as regras que contribuíram entram em cooldown.
+type Engine struct {
+	Threshold float64
+	DryRun    bool
+
+	mu       sync.Mutex
+	rules    []*ruleState
+	loudness float64 // Média móvel do volume (dBFS)
+	frames   int
+}
+
+// NewEngine compila as regras (as desligadas ou inválidas ficam de fora)
+func NewEngine(rules []models.HighlightRule, threshold float64, dryRun bool) *Engine {
+	if threshold <= 0 {
+		threshold = 1
+	}
+	e := &Engine{Threshold: threshold, DryRun: dryRun}
+	for _, r := range rules {
+		if r.Disabled || Normalize(&r) != nil {
+			continue
+		}
+		st := &ruleState{rule: r}
+		switch r.Kind {
+		case models.HighlightKeyword:
+			for _, w := range r.Keywords() {
+				st.words = append(st.words, strings.ToLower(w))
+			}
+		case models.HighlightRegex:
+			st.re = regexp.MustCompile(r.Pattern)
+		}
+		e.rules = append(e.rules, st)
+	}
+	return e
+}
+
+// Observe registra o sinal e devolve a decisão de corte, ou nil
+func (e *Engine) Observe(s Signal) *Decision {
+	if s.At.IsZero() {
+		s.At = time.Now()
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var spike float64
+	if s.Kind == SignalAudio {
+		spike = e.observeLoudness(s.Loudness)
+	}
+
+	for _, st := range e.rules {
+		value, ok := st.measure(s, spike)
+		if !ok || value < st.rule.Threshold {
+			continue
+		}
+		if time.Duration(st.rule.Cooldown)*time.Second > s.At.Sub(st.lastCut) {
+			continue
+		}
+		st.hit = &Hit{Rule: st.rule.Name, Kind: st.rule.Kind, Value: value}
+		st.hitAt = s.At
+	}
+
+	dec := &Decision{DryRun: e.DryRun}
+	for _, st := range e.rules {
+		if st.hit == nil {
+			continue
+		}
+		if s.At.Sub(st.hitAt) > CombineWindow {
+			st.hit = nil
+			continue
+		}
+		dec.Score += st.rule.Weight
+		dec.Hits = append(dec.Hits, *st.hit)
+	}
+	if len(dec.Hits) == 0 || dec.Score < e.Threshold {
+		return nil
+	}
+
+	for _, st := range e.rules {
+		if st.hit != nil {
+			st.lastCut = s.At
+			st.hit = nil
+		}
+	}
+	return dec
+}
+
+// observeLoudness compara o trecho com a média recente e devolve o pico em dB (0 no aquecimento)
+func (e *Engine) observeLoudness(db float64) float64 {
+	e.frames++
+	if e.frames == 1 {
+		e.loudness = db
+		return 0
+	}
+	spike := db - e.loudness
+	e.loudness += loudnessAlpha * (db - e.loudness)
+	if e.frames <= loudnessWarmup {
+		return 0
+	}
+	return spike
+}
+
+// measure devolve o valor da regra para o sinal (false se a regra não olha esse tipo de sinal)
+func (st *ruleState) measure(s Signal, spike float64) (float64, bool) {
+	switch st.rule.Kind {
+	case models.HighlightKeyword, models.HighlightRegex:
+		if s.Kind != SignalCaption {
+			return 0, false
+		}
+		text := s.Translation
+		if st.rule.Field == models.HighlightFieldTranscript {
+			text = s.Transcript
+		}
+		if st.re != nil {
+			return float64(len(st.re.FindAllStringIndex(text, -1))), true
+		}
+		text = strings.ToLower(text)
+		found := 0
+		for _, w := range st.words {
+			found += strings.Count(text, w)
+		}
+		return float64(found), true
+	case models.HighlightLoudness:
+		return spike, s.Kind == SignalAudio
+	case models.HighlightCaptionRate:
+		if s.Kind != SignalCaption {
+			return 0, false
+		}
+		return st.count(s.At, 1), true
+	case models.HighlightReactions:
+		if s.Kind != SignalReaction {
+			return 0, false
+		}
+		return st.count(s.At, s.Count), true
+	}
+	return 0, false
+}
+
+// count soma a ocorrência na janela deslizante da regra
+func (st *ruleState) count(at time.Time, n int) float64 {
+	window := time.Duration(st.rule.Window) * time.Second
+	keep := st.events[:0]
+	total := 0
+	for _, ev := range st.events {
+		if at.Sub(ev.at) < window {
+			keep = append(keep, ev)
+			total += ev.count
+		}
+	}
+	st.events = append(keep, event{at: at, count: n})
+	return float64(total + n)
+}
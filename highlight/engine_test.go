@@ -0,0 +1,127 @@
+package highlight
+
+import (
+	"k-lens/models"
+	"testing"
+	"time"
+)
+
+var t0 = time.Unix(1_800_000_000, 0)
+
+func caption(at time.Duration, text string) Signal {
+	return Signal{Kind: SignalCaption, At: t0.Add(at), Translation: text}
+}
+
+func TestEngineCombinesRulesWithinWindow(t *testing.T) {
+	rules := []models.HighlightRule{
+		{Name: "tchau", Kind: models.HighlightKeyword, Pattern: "tchau", Weight: 0.5, Cooldown: 60},
+		{Name: "reações", Kind: models.HighlightReactions, Threshold: 3, Window: 5, Weight: 0.5, Cooldown: 60},
+	}
+
+	e := NewEngine(rules, 1, false)
+	if d := e.Observe(caption(0, "tchau ARMY")); d != nil {
+		t.Fatalf("meio ponto não alcança o threshold: %+v", d)
+	}
+	d := e.Observe(Signal{Kind: SignalReaction, At: t0.Add(5 * time.Second), Count: 3})
+	if d == nil || d.Score != 1 || len(d.Hits) != 2 {
+		t.Fatalf("as duas regras deveriam somar dentro da janela: %+v", d)
+	}
+	if d.Reason() != "tchau=1, reações=3" {
+		t.Fatalf("motivo: %q", d.Reason())
+	}
+
+	// Fora da CombineWindow o primeiro disparo já não conta
+	e = NewEngine(rules, 1, false)
+	e.Observe(caption(0, "tchau"))
+	if d := e.Observe(Signal{Kind: SignalReaction, At: t0.Add(CombineWindow + time.Second), Count: 3}); d != nil {
+		t.Fatalf("disparo vencido não pode somar: %+v", d)
+	}
+}
+
+func TestEngineCooldownAfterCut(t *testing.T) {
+	rules := []models.HighlightRule{{Name: "tchau", Kind: models.HighlightKeyword, Pattern: "tchau", Weight: 1, Cooldown: 60}}
+	e := NewEngine(rules, 1, true)
+
+	if d := e.Observe(caption(0, "tchau")); d == nil || !d.DryRun {
+		t.Fatalf("primeiro disparo deveria cortar (dry-run): %+v", d)
+	}
+	if d := e.Observe(caption(30*time.Second, "tchau de novo")); d != nil {
+		t.Fatalf("dentro do cooldown não corta: %+v", d)
+	}
+	if d := e.Observe(caption(61*time.Second, "tchau!")); d == nil {
+		t.Fatal("depois do cooldown deveria cortar de novo")
+	}
+
+	// Cooldown zero: corta toda vez
+	rules[0].Cooldown = 0
+	e = NewEngine(rules, 1, false)
+	for i := 0; i < 3; i++ {
+		if d := e.Observe(caption(time.Duration(i)*time.Second, "tchau")); d == nil {
+			t.Fatalf("sem cooldown o disparo %d deveria cortar", i+1)
+		}
+	}
+}
+
+func TestEngineZeroWeightNeverCutsAlone(t *testing.T) {
+	rules := []models.HighlightRule{{Name: "tchau", Kind: models.HighlightKeyword, Pattern: "tchau", Weight: 0, Cooldown: 60}}
+	e := NewEngine(rules, 1, false)
+	if d := e.Observe(caption(0, "tchau")); d != nil {
+		t.Fatalf("regra de peso zero não soma no placar: %+v", d)
+	}
+}
+
+func TestEngineLoudnessWarmupAndSpike(t *testing.T) {
+	rules := []models.HighlightRule{{Name: "grito", Kind: models.HighlightLoudness, Threshold: 12, Weight: 1}}
+	e := NewEngine(rules, 1, false)
+	audio := func(i int, db float64) *Decision {
+		return e.Observe(Signal{Kind: SignalAudio, At: t0.Add(time.Duration(i) * time.Second), Loudness: db})
+	}
+
+	// No aquecimento nem um pico forte corta: ainda não há média confiável
+	for i := 1; i <= loudnessWarmup; i++ {
+		db := -30.0
+		if i == 10 {
+			db = -5
+		}
+		if d := audio(i, db); d != nil {
+			t.Fatalf("trecho %d do aquecimento cortou: %+v", i, d)
+		}
+	}
+	if d := audio(loudnessWarmup+1, -24); d != nil {
+		t.Fatalf("6 dB acima da média não é pico: %+v", d)
+	}
+	d := audio(loudnessWarmup+2, -10)
+	if d == nil || d.Hits[0].Value < 12 {
+		t.Fatalf("20 dB acima da média deveria cortar: %+v", d)
+	}
+}
+
+func TestEngineCaptionRateSlidingWindow(t *testing.T) {
+	rules := []models.HighlightRule{{Name: "rajada", Kind: models.HighlightCaptionRate, Threshold: 3, Window: 10, Weight: 1}}
+
+	// Três legendas em 8s: cabem na janela de 10s
+	e := NewEngine(rules, 1, false)
+	e.Observe(caption(0, "a"))
+	e.Observe(caption(4*time.Second, "b"))
+	if d := e.Observe(caption(8*time.Second, "c")); d == nil || d.Hits[0].Value != 3 {
+		t.Fatalf("três legendas na janela deveriam cortar: %+v", d)
+	}
+
+	// Espaçadas de 6s: a primeira sai da janela antes da terceira chegar
+	e = NewEngine(rules, 1, false)
+	e.Observe(caption(0, "a"))
+	e.Observe(caption(6*time.Second, "b"))
+	if d := e.Observe(caption(12*time.Second, "c")); d != nil {
+		t.Fatalf("a janela deveria ter descartado a primeira legenda: %+v", d)
+	}
+}
+
+func TestNewEngineSkipsDisabledAndInvalidRules(t *testing.T) {
+	e := NewEngine([]models.HighlightRule{
+		{Name: "desligada", Kind: models.HighlightKeyword, Pattern: "tchau", Weight: 1, Disabled: true},
+		{Name: "inválida", Kind: models.HighlightRegex, Pattern: "(", Weight: 1},
+	}, 0, false)
+	if len(e.rules) != 0 || e.Threshold != 1 {
+		t.Fatalf("regras compiladas: %d, threshold %v", len(e.rules), e.Threshold)
+	}
+}
@@ -0,0 +1,23 @@
+package highlight
+
+import (
+	"math"
+)
+
+// silenceDB é o piso usado para buffers vazios ou silêncio digital
+const silenceDB = -96.0
+
+// Loudness mede o volume RMS de um trecho PCM16 little-endian em dBFS (0 = máximo)
+func Loudness(pcm []byte) float64 {
+	var sum float64
+	samples := 0
+	for i := 0; i+1 < len(pcm); i += 2 {
+		s := float64(int16(uint16(pcm[i])|uint16(pcm[i+1])<<8)) / 32768
+		sum += s * s
+		samples++
+	}
+	if samples == 0 || sum == 0 {
+		return silenceDB
+	}
+	return math.Max(10*math.Log10(sum/float64(samples)), silenceDB)
+}
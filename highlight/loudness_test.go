@@ -0,0 +1,24 @@
+package highlight
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLoudness(t *testing.T) {
+	full := make([]byte, 200)
+	for i := 0; i < len(full); i += 4 {
+		// Onda quadrada no máximo: RMS 1 = 0 dBFS
+		full[i], full[i+1] = 0xff, 0x7f
+		full[i+2], full[i+3] = 0x00, 0x80
+	}
+	if got := Loudness(full); math.Abs(got) > 0.01 {
+		t.Fatalf("escala cheia deveria dar ~0 dBFS, deu %.2f", got)
+	}
+	if got := Loudness(make([]byte, 200)); got != silenceDB {
+		t.Fatalf("silêncio digital: %.2f", got)
+	}
+	if got := Loudness(nil); got != silenceDB {
+		t.Fatalf("buffer vazio: %.2f", got)
+	}
+}
@@ -0,0 +1,117 @@
+package highlight
+
+import (
+	"errors"
+	"fmt"
+	"k-lens/models"
+	"regexp"
+	"strings"
+)
+
+// Limites de uma regra: evitam regras que cortam o tempo todo ou nunca esquecem
+const (
+	maxPattern  = 500
+	maxWindow   = 300  // Segundos
+	maxCooldown = 3600 // Segundos
+)
+
+// Peso e cooldown de uma regra que não diz os seus. Zero é um valor válido nos dois
+// (regra que só aparece no motivo do corte; regra sem cooldown), por isso não são
+// preenchidos pelo Normalize: quem monta a regra decide.
+const (
+	DefaultWeight   = 1.0
+	DefaultCooldown = 60 // Segundos
+)
+
+// Normalize valida a regra e preenche o threshold e a janela padrão de cada tipo (zero ali não faz sentido)
+func Normalize(r *models.HighlightRule) error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Kind = strings.ToLower(strings.TrimSpace(r.Kind))
+	r.Field = strings.ToLower(strings.TrimSpace(r.Field))
+	r.Pattern = strings.TrimSpace(r.Pattern)
+	if r.Name == "" {
+		r.Name = r.Kind
+	}
+	if len(r.Pattern) > maxPattern {
+		return fmt.Errorf("pattern com mais de %d caracteres", maxPattern)
+	}
+
+	switch r.Kind {
+	case models.HighlightKeyword, models.HighlightRegex:
+		if r.Field == "" {
+			r.Field = models.HighlightFieldTranslation
+		}
+		if r.Field != models.HighlightFieldTranslation && r.Field != models.HighlightFieldTranscript {
+			return errors.New("field deve ser translation ou transcript")
+		}
+		if r.Kind == models.HighlightKeyword && len(r.Keywords()) == 0 {
+			return errors.New("pattern deve listar ao menos uma palavra")
+		}
+		if r.Kind == models.HighlightRegex {
+			if r.Pattern == "" {
+				return errors.New("pattern é obrigatório")
+			}
+			if _, err := regexp.Compile(r.Pattern); err != nil {
+				return fmt.Errorf("regex inválida: %v", err)
+			}
+		}
+		defaultFloat(&r.Threshold, 1)
+		r.Window = 0
+	case models.HighlightLoudness:
+		r.Field, r.Pattern, r.Window = "", "", 0
+		defaultFloat(&r.Threshold, 12) // dB acima da média recente
+	case models.HighlightCaptionRate:
+		r.Field, r.Pattern = "", ""
+		defaultFloat(&r.Threshold, 5)
+		defaultInt(&r.Window, 10)
+	case models.HighlightReactions:
+		r.Field, r.Pattern = "", ""
+		defaultFloat(&r.Threshold, 20)
+		defaultInt(&r.Window, 5)
+	default:
+		return fmt.Errorf("kind desconhecido: %q (use keyword, regex, loudness, caption_rate ou reactions)", r.Kind)
+	}
+
+	if r.Threshold < 0 || r.Weight < 0 || r.Window < 0 || r.Cooldown < 0 {
+		return errors.New("weight, threshold, window e cooldown não podem ser negativos")
+	}
+	if r.Window > maxWindow {
+		return fmt.Errorf("window acima de %d segundos", maxWindow)
+	}
+	if r.Cooldown > maxCooldown {
+		return fmt.Errorf("cooldown acima de %d segundos", maxCooldown)
+	}
+	return nil
+}
+
+// DefaultRules valem para lives sem regras próprias: despedidas e carinho,
+// em todos os idiomas de legenda e no coreano original.
+func DefaultRules() []models.HighlightRule {
+	rules := []models.HighlightRule{
+		{
+			Name: "despedida/carinho (tradução)", Kind: models.HighlightKeyword, Field: models.HighlightFieldTranslation,
+			Pattern: "💜,tchau,obrigado,obrigada,bye,thank you,adiós,gracias",
+		},
+		{
+			Name: "despedida/carinho (coreano)", Kind: models.HighlightKeyword, Field: models.HighlightFieldTranscript,
+			Pattern: "사랑해,고마워,안녕",
+		},
+	}
+	for i := range rules {
+		rules[i].Weight, rules[i].Cooldown = DefaultWeight, DefaultCooldown
+		Normalize(&rules[i])
+	}
+	return rules
+}
+
+func defaultFloat(v *float64, def float64) {
+	if *v == 0 {
+		*v = def
+	}
+}
+
+func defaultInt(v *int, def int) {
+	if *v == 0 {
+		*v = def
+	}
+}
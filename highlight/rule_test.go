@@ -0,0 +1,92 @@
+package highlight
+
+import (
+	"k-lens/models"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeDefaults(t *testing.T) {
+	cases := []struct {
+		name string
+		rule models.HighlightRule
+		want models.HighlightRule
+	}{
+		{
+			name: "keyword",
+			rule: models.HighlightRule{Kind: " Keyword ", Pattern: " tchau, bye ", Window: 30},
+			want: models.HighlightRule{Name: "keyword", Kind: "keyword", Field: "translation", Pattern: "tchau, bye", Threshold: 1},
+		},
+		{
+			name: "regex no coreano",
+			rule: models.HighlightRule{Name: " grito ", Kind: "regex", Field: "Transcript", Pattern: "아+", Threshold: 2},
+			want: models.HighlightRule{Name: "grito", Kind: "regex", Field: "transcript", Pattern: "아+", Threshold: 2},
+		},
+		{
+			name: "loudness",
+			rule: models.HighlightRule{Kind: "loudness", Field: "translation", Pattern: "x", Window: 5},
+			want: models.HighlightRule{Name: "loudness", Kind: "loudness", Threshold: 12},
+		},
+		{
+			name: "caption_rate",
+			rule: models.HighlightRule{Kind: "caption_rate"},
+			want: models.HighlightRule{Name: "caption_rate", Kind: "caption_rate", Threshold: 5, Window: 10},
+		},
+		{
+			name: "reactions",
+			rule: models.HighlightRule{Kind: "reactions", Threshold: 50},
+			want: models.HighlightRule{Name: "reactions", Kind: "reactions", Threshold: 50, Window: 5},
+		},
+		{
+			name: "peso e cooldown zero ficam zero",
+			rule: models.HighlightRule{Kind: "reactions", Weight: 0, Cooldown: 0},
+			want: models.HighlightRule{Name: "reactions", Kind: "reactions", Threshold: 20, Window: 5},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := tc.rule
+			if err := Normalize(&rule); err != nil {
+				t.Fatal(err)
+			}
+			if rule != tc.want {
+				t.Fatalf("regra %+v, esperava %+v", rule, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	cases := []struct {
+		name string
+		rule models.HighlightRule
+		want string
+	}{
+		{"kind desconhecido", models.HighlightRule{Kind: "mágica"}, "kind desconhecido"},
+		{"field inválido", models.HighlightRule{Kind: "keyword", Field: "chat", Pattern: "oi"}, "field"},
+		{"keyword sem palavras", models.HighlightRule{Kind: "keyword", Pattern: " , "}, "ao menos uma palavra"},
+		{"regex vazia", models.HighlightRule{Kind: "regex"}, "obrigatório"},
+		{"regex inválida", models.HighlightRule{Kind: "regex", Pattern: "(a"}, "regex inválida"},
+		{"pattern longo", models.HighlightRule{Kind: "keyword", Pattern: strings.Repeat("a", maxPattern+1)}, "pattern com mais"},
+		{"peso negativo", models.HighlightRule{Kind: "loudness", Weight: -1}, "negativos"},
+		{"cooldown negativo", models.HighlightRule{Kind: "loudness", Cooldown: -5}, "negativos"},
+		{"janela longa", models.HighlightRule{Kind: "reactions", Window: maxWindow + 1}, "window acima"},
+		{"cooldown longo", models.HighlightRule{Kind: "loudness", Cooldown: maxCooldown + 1}, "cooldown acima"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := tc.rule
+			if err := Normalize(&rule); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("erro %v, esperava %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestDefaultRulesUseDefaultWeightAndCooldown(t *testing.T) {
+	for _, r := range DefaultRules() {
+		if r.Weight != DefaultWeight || r.Cooldown != DefaultCooldown || r.Threshold != 1 {
+			t.Fatalf("regra padrão %q: %+v", r.Name, r)
+		}
+	}
+}
@@ -6,23 +6,64 @@ import (
 
 // Message define a estrutura de dados que viaja via WebSocket
 type Message struct {
-	Type    string      `json:"type"` // "translation", "ad", "system", "vip_alert", "CLIP_READY"
+	Type    string      `json:"type"` // "caption_partial", "caption_final", "translation", "ad", "system", "CLIP_READY"
 	Payload interface{} `json:"payload"`
-	LiveID  string      `json:"live_id,omitempty"` // Identificador da live para o "tubo" correto
-	Url     string      `json:"url,omitempty"`     // CAMPO ADICIONADO: Para o link de download do clipe
+	LiveID  string      `json:"live_id,omitempty"`  // Sala de destino; vazio = todas as salas
+	Url     string      `json:"url,omitempty"`      // CAMPO ADICIONADO: Para o link de download do clipe
+	StartMs int64       `json:"start_ms,omitempty"` // Início da fala (ms desde o início da live)
+	EndMs   int64       `json:"end_ms,omitempty"`   // Fim da fala
+
+	// Language restringe legendas a quem escolheu esse idioma (vazio = todos)
+	Language string `json:"language,omitempty"`
+	// CaptionID liga caption_partial à caption_final da mesma fala
+	CaptionID string `json:"caption_id,omitempty"`
+	// Speaker é o membro que está falando (a legenda já vem com "[Nome] ")
+	Speaker string `json:"speaker,omitempty"`
+
+	// Audience restringe a entrega a um nível de assinante (vazio = todos)
+	Audience string `json:"-"`
+}
+
+// Níveis de entrega
+const (
+	AudienceAll   = ""
+	AudienceVIP   = "vip"   // VIPs e equipe da live (host/moderador)
+	AudienceFree  = "free"  // Só quem não é VIP
+	AudienceStaff = "staff" // Só host/moderadores (fila de revisão, parciais retidas)
+)
+
+// Client é uma conexão inscrita na sala de uma live
+type Client struct {
+	LiveID string
+	UserID uint
+	Role   string // host, moderator ou viewer
+	VIP    bool   // Recebe o feed VIP (tempo real + conteúdo exclusivo)
+	Staff  bool   // Equipe da live: recebe a fila de revisão
+	Send   chan Message
+
+	// Idioma de legenda escolhido; altere só via Hub.SetLanguage depois do Register
+	Language string
+}
+
+// NewClient cria um cliente com buffer próprio para a sala indicada
+func NewClient(liveID string) *Client {
+	return &Client{
+		LiveID: liveID,
+		Send:   make(chan Message, 256),
+	}
 }
 
-// Hub mantém o conjunto de clientes ativos e faz o broadcast das mensagens
+// Hub mantém as salas (uma por live) e entrega cada mensagem apenas à sala certa
 type Hub struct {
-	// Clientes conectados: o canal de mensagens é a chave
-	Clients map[chan Message]bool
+	// Salas ativas: live ID -> clientes conectados
+	Rooms map[string]map[*Client]bool
 
-	// Mensagens que chegam para serem enviadas a todos
+	// Mensagens a serem entregues na sala indicada por Message.LiveID
 	Broadcast chan Message
 
 	// Canais para registrar e remover clientes (thread-safe)
-	Register   chan chan Message
-	Unregister chan chan Message
+	Register   chan *Client
+	Unregister chan *Client
 
 	mu sync.Mutex
 }
@@ -30,9 +71,9 @@ type Hub struct {
 func NewHub() *Hub {
 	return &Hub{
 		Broadcast:  make(chan Message),
-		Register:   make(chan chan Message),
-		Unregister: make(chan chan Message),
-		Clients:    make(map[chan Message]bool),
+		Register:   make(chan *Client),
+		Unregister: make(chan *Client),
+		Rooms:      make(map[string]map[*Client]bool),
 	}
 }
 
@@ -41,30 +82,107 @@ func (h *Hub) Run() {
 		select {
 		case client := <-h.Register:
 			h.mu.Lock()
-			h.Clients[client] = true
+			room, ok := h.Rooms[client.LiveID]
+			if !ok {
+				room = make(map[*Client]bool)
+				h.Rooms[client.LiveID] = room
+			}
+			room[client] = true
 			h.mu.Unlock()
 
 		case client := <-h.Unregister:
 			h.mu.Lock()
-			if _, ok := h.Clients[client]; ok {
-				delete(h.Clients, client)
-				close(client)
-			}
+			h.remove(client)
 			h.mu.Unlock()
 
 		case message := <-h.Broadcast:
 			h.mu.Lock()
-			for client := range h.Clients {
-				select {
-				case client <- message:
-					// Mensagem enviada com sucesso
-				default:
-					// Se o buffer do cliente estiver cheio, desconecta para não travar o hub
-					close(client)
-					delete(h.Clients, client)
+			if message.LiveID == "" {
+				for _, room := range h.Rooms {
+					h.deliver(room, message)
 				}
+			} else if room, ok := h.Rooms[message.LiveID]; ok {
+				h.deliver(room, message)
 			}
 			h.mu.Unlock()
 		}
 	}
 }
+
+// RoomSize retorna quantos clientes estão conectados na sala da live
+func (h *Hub) RoomSize(liveID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.Rooms[liveID])
+}
+
+// SendTo entrega uma mensagem só para um cliente (ex: erros da própria conexão).
+// Retorna false se o cliente já saiu da sala ou está com o buffer cheio.
+func (h *Hub) SendTo(client *Client, message Message) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.Rooms[client.LiveID][client] || !client.accepts(message) {
+		return false
+	}
+	select {
+	case client.Send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetLanguage troca o idioma de legenda do cliente sem disputar com as entregas em andamento
+func (h *Hub) SetLanguage(client *Client, language string) {
+	h.mu.Lock()
+	client.Language = language
+	h.mu.Unlock()
+}
+
+// deliver envia a mensagem para todos os clientes da sala. Deve ser chamado com h.mu travado.
+func (h *Hub) deliver(room map[*Client]bool, message Message) {
+	for client := range room {
+		if !client.accepts(message) {
+			continue
+		}
+		select {
+		case client.Send <- message:
+			// Mensagem enviada com sucesso
+		default:
+			// Se o buffer do cliente estiver cheio, desconecta para não travar o hub
+			h.remove(client)
+		}
+	}
+}
+
+// accepts indica se a mensagem é do nível de assinante (e do idioma) do cliente
+func (c *Client) accepts(message Message) bool {
+	if message.Language != "" && c.Language != "" && message.Language != c.Language {
+		return false
+	}
+	switch message.Audience {
+	case AudienceVIP:
+		return c.VIP
+	case AudienceFree:
+		return !c.VIP
+	case AudienceStaff:
+		return c.Staff
+	}
+	return true
+}
+
+// remove tira o cliente da sala e desmonta a sala quando ela esvazia.
+// Deve ser chamado com h.mu travado.
+func (h *Hub) remove(client *Client) {
+	room, ok := h.Rooms[client.LiveID]
+	if !ok {
+		return
+	}
+	if _, ok := room[client]; ok {
+		delete(room, client)
+		close(client.Send)
+	}
+	if len(room) == 0 {
+		delete(h.Rooms, client.LiveID)
+	}
+}
@@ -27,35 +27,35 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// 1. Inicialização do Gemini 2.0 Flash
-	geminiKey := os.Getenv("GEMINI_API_KEY")
-	if geminiKey == "" {
-		log.Fatal("❌ ERRO CRÍTICO: GEMINI_API_KEY não definida. Defina a variável de ambiente antes de iniciar.")
-	}
-
-	geminiSvc, err := translate.NewGeminiService(ctx, geminiKey)
+	// 1. Inicialização do tradutor (TRANSLATE_PROVIDER: gemini, fake ou local)
+	translator, err := translate.NewFromEnv(ctx)
 	if err != nil {
-		log.Fatalf("❌ Erro crítico ao iniciar Gemini: %v", err)
+		log.Fatalf("❌ Erro crítico ao iniciar tradutor: %v", err)
 	}
-	defer geminiSvc.Close()
+	defer translator.Close()
 
 	// 2. Banco de Dados e Hub de WebSockets
 	db.InitDB()
 	legendasHub := hub.NewHub()
 	go legendasHub.Run()
+	handler.StartClipPipeline(ctx, legendasHub)
+	handler.StartBilling(ctx)
 
 	r := mux.NewRouter()
 
 	// --- CAMADA DE SEGURANÇA (MIDDLEWARE) ---
-	// Isso protege contra ataques e exige o token ?token=... definido no seu .env
+	// Headers de segurança + exige sessão ou token assinado (convite) em /api, /ws e /recordings
 	r.Use(handler.SecurityMiddleware)
 
 	// --- ROTAS DE AUTENTICAÇÃO ---
 	r.HandleFunc("/auth/google/login", handler.HandleGoogleLogin)
+	r.HandleFunc("/auth/google/callback", handler.HandleGoogleCallback)
+	r.HandleFunc("/auth/logout", handler.HandleLogout).Methods("POST")
+	r.HandleFunc("/api/me", handler.HandleMe).Methods("GET")
 
 	// --- ROTA PARA DOWNLOAD DOS CORTES ---
-	// Protegido pelo middleware: o celular precisa do token para baixar o vídeo
-	r.PathPrefix("/recordings/").Handler(http.StripPrefix("/recordings/", http.FileServer(http.Dir("./recordings"))))
+	// Só clipes do catálogo, com o acesso da live dele (VIP ou equipe)
+	r.HandleFunc("/recordings/{file}", handler.ServeRecording).Methods("GET", "HEAD")
 
 	// --- HEALTH CHECK (Google Cloud Load Balancer) ---
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -64,13 +64,78 @@ func main() {
 		w.Write([]byte("OK"))
 	}).Methods("GET")
 
+	// --- MÉTRICAS (rate limit e descartes do tradutor, formato Prometheus; Bearer METRICS_TOKEN) ---
+	r.HandleFunc("/metrics", handler.Metrics).Methods("GET")
+
 	// --- ROTA WEBSOCKET (O coração do Studio) ---
 	r.HandleFunc("/ws/studio/{id}", func(w http.ResponseWriter, r *http.Request) {
-		handler.ServeWS(legendasHub, geminiSvc, w, r)
+		handler.ServeWS(legendasHub, translator, w, r)
 	})
 
 	// --- API TRADUÇÃO REVERSA ---
-	r.HandleFunc("/api/translate-reverse", handler.ReverseTranslate).Methods("POST", "OPTIONS")
+	r.HandleFunc("/api/translate-reverse", func(w http.ResponseWriter, r *http.Request) {
+		handler.ReverseTranslate(translator, w, r)
+	}).Methods("POST", "OPTIONS")
+
+	// --- API DE LIVES (ciclo de vida do LiveArchive) ---
+	r.HandleFunc("/api/lives", handler.CreateLive).Methods("POST")
+	r.HandleFunc("/api/lives", handler.ListLives).Methods("GET")
+	r.HandleFunc("/api/lives/{id}", handler.GetLive).Methods("GET")
+	r.HandleFunc("/api/lives/{id}/start", func(w http.ResponseWriter, r *http.Request) {
+		handler.StartLive(legendasHub, w, r)
+	}).Methods("POST")
+	r.HandleFunc("/api/lives/{id}/end", func(w http.ResponseWriter, r *http.Request) {
+		handler.EndLive(legendasHub, w, r)
+	}).Methods("POST")
+
+	r.HandleFunc("/api/lives/{id}/languages", handler.SetLiveLanguages).Methods("PUT")
+	r.HandleFunc("/api/lives/{id}/roster", handler.SetLiveRoster).Methods("PUT")
+	r.HandleFunc("/api/lives/{id}/review_delay", handler.SetLiveReviewDelay).Methods("PUT")
+	// Catálogo de clipes
+	r.HandleFunc("/api/lives/{id}/clips", handler.ListClips).Methods("GET")
+	r.HandleFunc("/api/lives/{id}/clips/{clip}", handler.GetClip).Methods("GET")
+	r.HandleFunc("/api/lives/{id}/clips/{clip}", handler.RenameClip).Methods("PATCH")
+	r.HandleFunc("/api/lives/{id}/clips/{clip}", handler.DeleteClip).Methods("DELETE")
+	// Regras de corte automático
+	r.HandleFunc("/api/lives/{id}/highlights", handler.ListHighlightRules).Methods("GET")
+	r.HandleFunc("/api/lives/{id}/highlights", handler.SetHighlightSettings).Methods("PUT")
+	r.HandleFunc("/api/lives/{id}/highlights/rules", handler.CreateHighlightRule).Methods("POST")
+	r.HandleFunc("/api/lives/{id}/highlights/rules/{rule}", handler.UpdateHighlightRule).Methods("PUT")
+	r.HandleFunc("/api/lives/{id}/highlights/rules/{rule}", handler.DeleteHighlightRule).Methods("DELETE")
+	r.HandleFunc("/api/lives/{id}/members", handler.ListLiveMembers).Methods("GET")
+	r.HandleFunc("/api/lives/{id}/members", handler.SetLiveMember).Methods("PUT")
+
+	// --- CONVITES (links de viewer com expiração, revogáveis) ---
+	r.HandleFunc("/api/lives/{id}/invites", handler.CreateInvite).Methods("POST")
+	r.HandleFunc("/api/lives/{id}/invites", handler.ListInvites).Methods("GET")
+	r.HandleFunc("/api/lives/{id}/invites/{invite}", handler.RevokeInvite).Methods("DELETE")
+
+	// --- GLOSSÁRIO DO FANDOM (grafias obrigatórias nas legendas) ---
+	r.HandleFunc("/api/glossary", handler.ListGlossary).Methods("GET")
+	r.HandleFunc("/api/glossary", handler.CreateGlossaryTerm).Methods("POST")
+	r.HandleFunc("/api/glossary/{term}", handler.UpdateGlossaryTerm).Methods("PUT")
+	r.HandleFunc("/api/glossary/{term}", handler.DeleteGlossaryTerm).Methods("DELETE")
+	r.HandleFunc("/api/translators", handler.SetTranslator).Methods("PUT")
+
+	// --- API DE LEGENDAS (exportação do arquivo da live) ---
+	r.HandleFunc("/api/lives/{id}/captions/{format}", handler.ExportCaptions).Methods("GET")
+
+	// --- CORREÇÃO DE LEGENDAS (moderadores; histórico em CaptionRevision) ---
+	r.HandleFunc("/api/lives/{id}/captions/{caption}", func(w http.ResponseWriter, r *http.Request) {
+		handler.EditCaption(legendasHub, w, r)
+	}).Methods("PUT")
+	r.HandleFunc("/api/lives/{id}/captions/{caption}", func(w http.ResponseWriter, r *http.Request) {
+		handler.DeleteCaption(legendasHub, w, r)
+	}).Methods("DELETE")
+	r.HandleFunc("/api/lives/{id}/captions/{caption}/revisions", handler.ListCaptionRevisions).Methods("GET")
+	// Fila de revisão (lives com review_delay)
+	r.HandleFunc("/api/lives/{id}/review", handler.ListPendingCaptions).Methods("GET")
+	r.HandleFunc("/api/lives/{id}/captions/{caption}/approve", func(w http.ResponseWriter, r *http.Request) {
+		handler.ApproveCaption(legendasHub, w, r)
+	}).Methods("POST")
+
+	// --- WEBHOOK DE COBRANÇA (assinatura HMAC própria) ---
+	r.HandleFunc("/webhooks/billing", handler.BillingWebhook).Methods("POST")
 
 	// --- ARQUIVOS ESTÁTICOS (Frontend) ---
 	// Deve ficar por último para não interceptar as rotas acima
@@ -101,8 +166,8 @@ func main() {
 	localIP := getLocalIP()
 	log.Printf("==========================================")
 	log.Printf("🚀 K-LENS ARMY STUDIO (Modo Seguro Ativo)")
-	log.Printf("🔗 Link Studio: http://localhost:%s/studio.html?token=%s", port, os.Getenv("APP_SECRET_TOKEN"))
-	log.Printf("📱 Mobile: http://%s:%s/studio.html?token=%s", localIP, port, os.Getenv("APP_SECRET_TOKEN"))
+	log.Printf("🔗 Link Studio: http://localhost:%s/studio.html", port)
+	log.Printf("📱 Mobile: http://%s:%s/studio.html (convites via POST /api/lives/{id}/invites)", localIP, port)
 	log.Printf("==========================================")
 
 	if err := server.ListenAndServe(); err != http.ErrServerClosed {
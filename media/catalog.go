@@ -0,0 +1,117 @@
+package media
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"k-lens/db"
+	"k-lens/models"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// storageName gera um nome de arquivo único para o clipe: live, rótulo, proporção,
+// horário e um sufixo aleatório (dois cortes no mesmo segundo não se sobrescrevem)
+func (c *Cutter) storageName(job *models.ClipJob) string {
+	safeRatio := strings.ReplaceAll(job.Settings.AspectRatio, ":", "x")
+	for {
+		suffix := make([]byte, 4)
+		rand.Read(suffix)
+		name := fmt.Sprintf("KLENS_%s_%s_%s_%s_%s.mp4",
+			sanitizeDirName(job.LiveID), job.Label, safeRatio, time.Now().Format("20060102-150405"), hex.EncodeToString(suffix))
+		if _, err := os.Stat(filepath.Join(c.StoragePath, name)); os.IsNotExist(err) {
+			return name
+		}
+	}
+}
+
+// catalog mantém o models.Clip do pedido em dia com o andamento dele.
+// Ao concluir, grava tamanho e SHA-256 do arquivo.
+func (c *Cutter) catalog(job *models.ClipJob) {
+	if db.DB == nil || job.FileName == "" {
+		return
+	}
+
+	var clip models.Clip
+	err := db.DB.Where("clip_job_id = ?", job.ID).First(&clip).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		log.Printf("⚠️ [Catálogo] Erro ao buscar clipe do pedido %d: %v", job.ID, err)
+		return
+	}
+	if clip.ID == 0 {
+		liveID, _ := strconv.ParseUint(job.LiveID, 10, 32)
+		start := int64(job.Timestamp) - int64(job.Settings.PreRoll)*1000
+		if start < 0 {
+			start = 0
+		}
+		clip = models.Clip{
+			LiveArchiveID: uint(liveID),
+			ClipJobID:     job.ID,
+			FileName:      job.FileName,
+			Title:         job.Label,
+			StartMs:       start,
+			EndMs:         start + int64(job.Settings.Duration)*1000,
+			Duration:      job.Settings.Duration,
+			AspectRatio:   job.Settings.AspectRatio,
+			Label:         job.Label,
+			Reason:        job.Reason,
+		}
+	}
+
+	switch job.Status {
+	case models.ClipJobDone:
+		clip.Status = models.ClipReady
+		clip.FileSize, clip.Checksum, err = fileDigest(c.ClipPath(job.FileName))
+		if err != nil {
+			log.Printf("⚠️ [Catálogo] Erro ao medir %s: %v", job.FileName, err)
+		}
+	case models.ClipJobFailed:
+		clip.Status = models.ClipFailed
+	default:
+		clip.Status = models.ClipProcessing
+	}
+
+	if err := db.DB.Save(&clip).Error; err != nil {
+		log.Printf("⚠️ [Catálogo] Erro ao salvar clipe do pedido %d: %v", job.ID, err)
+	}
+}
+
+// ClipPath devolve o caminho do arquivo no storage (só o nome base é aceito)
+func (c *Cutter) ClipPath(fileName string) string {
+	return filepath.Join(c.StoragePath, filepath.Base(fileName))
+}
+
+// RemoveClipFiles apaga o mp4 e as legendas sidecar (.srt/.vtt) do clipe
+func (c *Cutter) RemoveClipFiles(fileName string) error {
+	path := c.ClipPath(fileName)
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, p := range []string{path, base + ".srt", base + ".vtt"} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func fileDigest(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
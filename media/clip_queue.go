@@ -0,0 +1,233 @@
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"k-lens/db"
+	"k-lens/models"
+	"log"
+	"time"
+)
+
+// Start sobe o pool de workers de corte e retoma os pedidos que ficaram
+// pendentes no banco (ex: servidor reiniciado no meio de um encode).
+func (c *Cutter) Start(ctx context.Context, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	c.ctx = ctx
+
+	for i := 0; i < workers; i++ {
+		go c.worker(ctx)
+	}
+	log.Printf("🎞️ [Cutter] %d workers de corte ativos", workers)
+
+	c.resume()
+}
+
+func (c *Cutter) worker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-c.queue:
+			c.process(ctx, job)
+		}
+	}
+}
+
+// process leva um pedido de queued até done/failed, reagendando em caso de erro
+func (c *Cutter) process(ctx context.Context, job *models.ClipJob) {
+	job.Attempts++
+	c.setStatus(job, models.ClipJobResolving)
+
+	// Preferência: cortar do buffer local, que é exatamente o que foi ao ar
+	if c.DVR.Enabled() && !job.TriggeredAt.IsZero() {
+		clipName, err := c.encodeFromDVR(ctx, job)
+		var wait *dvrWait
+		if errors.As(err, &wait) {
+			// Não conta como tentativa: o worker fica livre e o pedido volta na hora certa
+			job.Attempts--
+			job.NextAttemptAt = wait.until
+			c.setStatus(job, models.ClipJobQueued)
+			c.schedule(job)
+			return
+		}
+		if err == nil {
+			job.FileName = clipName
+			job.LastError = ""
+			c.setStatus(job, models.ClipJobDone)
+			return
+		}
+		if ctx.Err() != nil {
+			c.fail(job, err, true)
+			return
+		}
+		log.Printf("⚠️ [Cutter] DVR indisponível para o pedido %d (%v), usando a fonte direta", job.ID, err)
+	}
+
+	urls, err := c.GetStreamURL(ctx, job.SourceURL)
+	if err == nil && len(urls) == 0 {
+		err = fmt.Errorf("yt-dlp não retornou URLs")
+	}
+	if err != nil {
+		log.Printf("❌ [Cutter] Erro ao obter URLs (pedido %d, tentativa %d): %v", job.ID, job.Attempts, err)
+		c.fail(job, err, true)
+		return
+	}
+
+	c.setStatus(job, models.ClipJobEncoding)
+	clipName, err := c.encode(ctx, job, urls)
+	if err != nil {
+		c.fail(job, err, true)
+		return
+	}
+
+	job.FileName = clipName
+	job.LastError = ""
+	c.setStatus(job, models.ClipJobDone)
+}
+
+// fail registra o erro e, se ainda houver tentativas, reagenda com backoff exponencial
+func (c *Cutter) fail(job *models.ClipJob, err error, retry bool) {
+	job.LastError = err.Error()
+
+	// Desligamento do servidor não conta como falha: o pedido é retomado no próximo start
+	if c.ctx.Err() != nil {
+		job.Attempts--
+		c.save(job)
+		return
+	}
+
+	if !retry || job.Attempts >= c.MaxAttempts {
+		c.setStatus(job, models.ClipJobFailed)
+		return
+	}
+
+	delay := c.RetryBase * time.Duration(1<<uint(job.Attempts-1))
+	job.NextAttemptAt = time.Now().Add(delay)
+	c.setStatus(job, models.ClipJobQueued)
+	log.Printf("🔁 [Cutter] Pedido %d reagendado em %s", job.ID, delay)
+	c.schedule(job)
+}
+
+// schedule coloca o pedido na fila respeitando NextAttemptAt
+func (c *Cutter) schedule(job *models.ClipJob) {
+	wait := time.Until(job.NextAttemptAt)
+	if wait <= 0 {
+		c.requeue(job)
+		return
+	}
+	time.AfterFunc(wait, func() { c.requeue(job) })
+}
+
+// requeue tenta a fila; cheia não é falha do pedido: ele continua queued e tenta
+// de novo depois de RetryBase (o estado fica no banco e sobrevive a um restart)
+func (c *Cutter) requeue(job *models.ClipJob) {
+	if err := c.enqueue(job); err == nil {
+		return
+	}
+	job.NextAttemptAt = time.Now().Add(c.RetryBase)
+	c.save(job)
+	log.Printf("⏳ [Cutter] Fila cheia, pedido %d tenta de novo em %s", job.ID, c.RetryBase)
+	time.AfterFunc(c.RetryBase, func() { c.requeue(job) })
+}
+
+func (c *Cutter) enqueue(job *models.ClipJob) error {
+	select {
+	case c.queue <- job:
+		return nil
+	default:
+		return fmt.Errorf("fila de cortes cheia")
+	}
+}
+
+// resume recoloca na fila tudo que não terminou antes do último desligamento
+func (c *Cutter) resume() {
+	if db.DB == nil {
+		return
+	}
+
+	var pending []models.ClipJob
+	err := db.DB.Where("status IN ?", models.ActiveClipJobStatuses).Order("id").Find(&pending).Error
+	if err != nil {
+		log.Printf("❌ [Cutter] Erro ao carregar pedidos pendentes: %v", err)
+		return
+	}
+
+	for i := range pending {
+		job := &pending[i]
+		c.holdDVR(job)
+		job.Status = models.ClipJobQueued
+		c.save(job)
+		c.schedule(job)
+	}
+	if len(pending) > 0 {
+		log.Printf("♻️ [Cutter] %d pedidos de corte retomados", len(pending))
+	}
+}
+
+// holdDVR segura o buffer da live enquanto o pedido (que corta pelo DVR) não termina
+func (c *Cutter) holdDVR(job *models.ClipJob) {
+	if !job.TriggeredAt.IsZero() {
+		c.DVR.Hold(job.LiveID)
+	}
+}
+
+func (c *Cutter) releaseDVR(job *models.ClipJob) {
+	if !job.TriggeredAt.IsZero() {
+		c.DVR.Release(job.LiveID)
+	}
+}
+
+func (c *Cutter) insert(job *models.ClipJob) error {
+	if db.DB == nil {
+		c.mu.Lock()
+		c.nextID++
+		job.ID = c.nextID
+		c.mu.Unlock()
+		return nil
+	}
+	if err := db.DB.Create(job).Error; err != nil {
+		return fmt.Errorf("erro ao registrar pedido de corte: %v", err)
+	}
+	c.catalog(job)
+	return nil
+}
+
+func (c *Cutter) save(job *models.ClipJob) {
+	if db.DB == nil {
+		return
+	}
+	if err := db.DB.Save(job).Error; err != nil {
+		log.Printf("⚠️ [Cutter] Erro ao salvar pedido %d: %v", job.ID, err)
+	}
+}
+
+func (c *Cutter) setStatus(job *models.ClipJob, status string) {
+	job.Status = status
+	c.save(job)
+	if status == models.ClipJobDone || status == models.ClipJobFailed {
+		c.catalog(job)
+		c.releaseDVR(job)
+	}
+	c.notify(job)
+}
+
+// notify nunca bloqueia: é chamado também do loop de leitura do websocket (via CreateClip).
+// Com o canal cheio o aviso é descartado; o estado do pedido continua salvo no banco.
+func (c *Cutter) notify(job *models.ClipJob) {
+	notice := ClipNotice{
+		LiveID:   job.LiveID,
+		JobID:    job.ID,
+		Status:   job.Status,
+		FileName: job.FileName,
+		Error:    job.LastError,
+	}
+	select {
+	case c.NotifyChan <- notice:
+	default:
+		log.Printf("⚠️ [Cutter] Canal de avisos cheio, aviso do pedido %d (%s) descartado", job.ID, job.Status)
+	}
+}
@@ -0,0 +1,25 @@
+package media
+
+import (
+	"k-lens/models"
+	"testing"
+	"time"
+)
+
+func TestNotifyDoesNotBlockWhenChannelIsFull(t *testing.T) {
+	c := &Cutter{NotifyChan: make(chan ClipNotice, 1)}
+	done := make(chan struct{})
+	go func() {
+		c.notify(&models.ClipJob{ID: 1, Status: models.ClipJobDone})
+		c.notify(&models.ClipJob{ID: 2, Status: models.ClipJobDone})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("notify travou com o canal cheio")
+	}
+	if notice := <-c.NotifyChan; notice.JobID != 1 {
+		t.Fatalf("aviso entregue: %+v", notice)
+	}
+}
@@ -0,0 +1,98 @@
+package media
+
+import (
+	"fmt"
+	"k-lens/models"
+	"regexp"
+	"strings"
+)
+
+// ClipSpec é a configuração imutável de um corte: cada pedido leva a sua,
+// então dois studios com proporções diferentes não interferem um no outro.
+type ClipSpec struct {
+	models.ClipSettings
+	Label  string
+	Reason string // Por que o corte foi pedido (regras de destaque que dispararam)
+}
+
+// DefaultClipSettings são os padrões usados antes de qualquer update_config
+func DefaultClipSettings() models.ClipSettings {
+	return models.ClipSettings{
+		Duration:    61,
+		PreRoll:     5,
+		AspectRatio: "9:16",
+		Watermark:   "K-LENS STUDIO",
+		Quality:     "medium",
+	}
+}
+
+// encodePreset mapeia a qualidade pedida para preset/CRF do libx264
+var encodePreset = map[string]struct {
+	Preset string
+	CRF    string
+}{
+	"low":    {"veryfast", "28"},
+	"medium": {"ultrafast", "23"},
+	"high":   {"medium", "20"},
+}
+
+var unsafeLabel = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// Normalize completa campos vazios com o padrão e valida os limites
+func (s ClipSpec) Normalize() (ClipSpec, error) {
+	s.ClipSettings = NormalizeSettings(s.ClipSettings)
+	if err := ValidateSettings(s.ClipSettings); err != nil {
+		return s, err
+	}
+
+	s.Label = unsafeLabel.ReplaceAllString(s.Label, "_")
+	if s.Label == "" {
+		s.Label = "clip"
+	}
+	return s, nil
+}
+
+// NormalizeSettings completa os campos zerados com DefaultClipSettings
+func NormalizeSettings(cs models.ClipSettings) models.ClipSettings {
+	def := DefaultClipSettings()
+	if cs.Duration == 0 {
+		cs.Duration = def.Duration
+	}
+	if cs.AspectRatio == "" {
+		cs.AspectRatio = def.AspectRatio
+	}
+	if cs.Quality == "" {
+		cs.Quality = def.Quality
+	}
+	if cs.Watermark == "" {
+		cs.Watermark = def.Watermark
+	}
+	cs.Subtitles = normalizeSubtitles(cs.Subtitles)
+	return cs
+}
+
+// ValidateSettings recusa valores que o ffmpeg não deve receber
+func ValidateSettings(cs models.ClipSettings) error {
+	if cs.Duration < 5 || cs.Duration > 180 {
+		return fmt.Errorf("duração fora do intervalo (5-180s): %d", cs.Duration)
+	}
+	if cs.PreRoll < 0 || cs.PreRoll > 60 {
+		return fmt.Errorf("pre-roll fora do intervalo (0-60s): %d", cs.PreRoll)
+	}
+	if cs.AspectRatio != "9:16" && cs.AspectRatio != "16:9" {
+		return fmt.Errorf("proporção não suportada: %s", cs.AspectRatio)
+	}
+	if _, ok := encodePreset[cs.Quality]; !ok {
+		return fmt.Errorf("qualidade não suportada: %s", cs.Quality)
+	}
+	if len([]rune(cs.Watermark)) > 40 {
+		return fmt.Errorf("marca d'água longa demais (máx. 40 caracteres)")
+	}
+	return validateSubtitles(cs.Subtitles)
+}
+
+// escapeDrawtext protege o texto da marca d'água dentro do filtro drawtext
+func escapeDrawtext(text string) string {
+	r := strings.NewReplacer(`\`, ``, `'`, ``, `:`, `\:`, `%`, `\%`, `,`, `\,`, `;`, `\;`)
+	return r.Replace(text)
+}
@@ -1,25 +1,42 @@
 package media
 
 import (
+	"context"
 	"fmt"
+	"k-lens/models"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
-type Config struct {
-	ClipDuration int
-	AspectRatio  string
+// ClipNotice avisa a live sobre o andamento de um pedido de corte
+type ClipNotice struct {
+	LiveID   string
+	JobID    uint
+	Status   string // models.ClipJob*
+	FileName string // Nome no storage (o arquivo só existe quando Status == done)
+	Error    string
 }
 
 type Cutter struct {
 	StoragePath string
-	CurrentConf Config
-	// Canal para avisar quando um clipe fica pronto
-	NotifyChan chan string
+	// Buffer local por live; quando cobre o trecho pedido, o corte sai dele
+	DVR *DVR
+	// Canal para avisar o andamento (e a conclusão) dos cortes
+	NotifyChan chan ClipNotice
+
+	// Fila de cortes: tentativas com backoff exponencial a partir de RetryBase
+	MaxAttempts int
+	RetryBase   time.Duration
+
+	queue  chan *models.ClipJob
+	ctx    context.Context
+	mu     sync.Mutex
+	nextID uint // IDs locais quando não há banco
 }
 
 func NewCutter() *Cutter {
@@ -27,14 +44,27 @@ func NewCutter() *Cutter {
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		os.MkdirAll(path, 0755)
 	}
-	return &Cutter{
+	c := &Cutter{
 		StoragePath: path,
-		CurrentConf: Config{ClipDuration: 61, AspectRatio: "9:16"},
-		NotifyChan:  make(chan string, 10),
+		NotifyChan:  make(chan ClipNotice, 64),
+		MaxAttempts: 3,
+		RetryBase:   5 * time.Second,
+		queue:       make(chan *models.ClipJob, 100),
+		ctx:         context.Background(),
 	}
+	c.DVR = NewDVRFromEnv(func(ctx context.Context, url string) ([]string, error) {
+		// O DVR precisa de um único stream com vídeo e áudio juntos
+		return c.resolve(ctx, url, "best")
+	})
+	return c
 }
 
-func (c *Cutter) GetStreamURL(youtubeURL string) ([]string, error) {
+func (c *Cutter) GetStreamURL(ctx context.Context, youtubeURL string) ([]string, error) {
+	// Busca URLs separadas de vídeo e áudio
+	return c.resolve(ctx, youtubeURL, "bestvideo+bestaudio/best")
+}
+
+func (c *Cutter) resolve(ctx context.Context, youtubeURL, format string) ([]string, error) {
 	log.Printf("🔍 [yt-dlp] Resolvendo URL: %s", youtubeURL)
 
 	// Usa exec.LookPath para encontrar yt-dlp no PATH (Windows, Linux, macOS)
@@ -44,8 +74,7 @@ func (c *Cutter) GetStreamURL(youtubeURL string) ([]string, error) {
 		ytDlpPath = "yt-dlp"
 	}
 
-	// Busca URLs separadas de vídeo e áudio
-	cmd := exec.Command(ytDlpPath, "--no-playlist", "-g", "-f", "bestvideo+bestaudio/best", youtubeURL)
+	cmd := exec.CommandContext(ctx, ytDlpPath, "--no-playlist", "-g", "-f", format, youtubeURL)
 	out, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("erro yt-dlp: %v", err)
@@ -55,75 +84,124 @@ func (c *Cutter) GetStreamURL(youtubeURL string) ([]string, error) {
 	return urls, nil
 }
 
-func (c *Cutter) UpdateConfig(duration int, ratio string) {
-	c.CurrentConf.ClipDuration = duration
-	c.CurrentConf.AspectRatio = ratio
-	log.Printf("⚙️ [Config] Nova meta de produção: %ds em %s", duration, ratio)
-}
+// CreateClip registra um pedido de corte e o coloca na fila dos workers.
+// A spec é copiada para o pedido: mudanças posteriores nos padrões da live não o afetam.
+// triggeredAt é o horário de parede do gatilho, usado para cortar do DVR.
+// O andamento chega pelo NotifyChan; o retorno traz o ID do pedido.
+func (c *Cutter) CreateClip(liveID string, youtubeURL string, timestamp float64, triggeredAt time.Time, spec ClipSpec) (*models.ClipJob, error) {
+	if youtubeURL == "" {
+		return nil, fmt.Errorf("nenhuma URL de live configurada para o corte")
+	}
+	spec, err := spec.Normalize()
+	if err != nil {
+		return nil, err
+	}
 
-func (c *Cutter) CreateClip(liveID string, youtubeURL string, timestamp float64, label string) {
-	go func() {
-		urls, err := c.GetStreamURL(youtubeURL)
-		if err != nil || len(urls) == 0 {
-			log.Printf("❌ [Cutter] Erro ao obter URLs: %v", err)
-			return
-		}
+	job := &models.ClipJob{
+		LiveID:      liveID,
+		SourceURL:   youtubeURL,
+		Timestamp:   timestamp,
+		TriggeredAt: triggeredAt,
+		Label:       spec.Label,
+		Reason:      spec.Reason,
+		Settings:    spec.ClipSettings,
+		Status:      models.ClipJobQueued,
+	}
+	job.FileName = c.storageName(job)
+	if err := c.insert(job); err != nil {
+		return nil, err
+	}
 
-		// 2. Calcular ponto de início (5s antes do gatilho)
-		startPoint := (timestamp / 1000.0) - 5.0
-		if startPoint < 0 {
-			startPoint = 0
-		}
+	c.holdDVR(job)
+	// Fila cheia não recusa o pedido: ele fica queued e entra quando houver vaga
+	c.notify(job)
+	c.schedule(job)
+	return job, nil
+}
 
-		safeRatio := strings.ReplaceAll(c.CurrentConf.AspectRatio, ":", "x")
-		clipName := fmt.Sprintf("KLENS_%s_%s_%s.mp4", label, safeRatio, time.Now().Format("150405"))
-		outputPath := filepath.Join(c.StoragePath, clipName)
+// encode roda o ffmpeg sobre as URLs resolvidas e devolve o nome do arquivo gerado
+func (c *Cutter) encode(ctx context.Context, job *models.ClipJob, urls []string) (string, error) {
+	cs := job.Settings
 
-		watermark := "K-LENS STUDIO"
-		var videoFilter string
-		if c.CurrentConf.AspectRatio == "9:16" {
-			videoFilter = fmt.Sprintf("crop=ih*9/16:ih,unsharp=3:3:1.5:3:3:0.5,drawtext=text='%s':fontcolor=white@0.8:fontsize=24:x=(w-tw)/2:y=60:shadowcolor=black:shadowx=2:shadowy=2", watermark)
-		} else {
-			videoFilter = fmt.Sprintf("scale=1920:1080:force_original_aspect_ratio=decrease,pad=1920:1080:(ow-iw)/2:(oh-ih)/2,drawtext=text='%s':fontcolor=white@0.8:fontsize=32:x=(w-tw)/2:y=50:shadowcolor=black:shadowx=2:shadowy=2", watermark)
-		}
+	// Ponto de início: pre-roll antes do gatilho
+	startPoint := (job.Timestamp / 1000.0) - float64(cs.PreRoll)
+	if startPoint < 0 {
+		startPoint = 0
+	}
 
-		// Argumentos otimizados para sincronia e reconexão
-		args := []string{
-			"-y",
-			"-reconnect", "1", "-reconnect_at_eof", "1", "-reconnect_streamed", "1", "-reconnect_delay_max", "5",
-			"-ss", fmt.Sprintf("%.2f", startPoint),
-		}
+	// Argumentos otimizados para sincronia e reconexão
+	args := []string{
+		"-y",
+		"-reconnect", "1", "-reconnect_at_eof", "1", "-reconnect_streamed", "1", "-reconnect_delay_max", "5",
+		"-ss", fmt.Sprintf("%.2f", startPoint),
+	}
 
-		// Adiciona cada URL como um input separado (-i url1 -i url2)
-		for _, u := range urls {
-			args = append(args, "-i", strings.TrimSpace(u))
-		}
+	// Adiciona cada URL como um input separado (-i url1 -i url2)
+	for _, u := range urls {
+		args = append(args, "-i", strings.TrimSpace(u))
+	}
+
+	args = append(args, "-t", fmt.Sprintf("%d", cs.Duration))
+	return c.runFFmpeg(ctx, job, args, len(urls))
+}
 
-		args = append(args,
-			"-t", fmt.Sprintf("%d", c.CurrentConf.ClipDuration),
-			"-filter_complex", "[0:v]"+videoFilter+"[outv]", // Filtro no vídeo do primeiro input
-			"-map", "[outv]", // Usa o vídeo filtrado
-			"-map", "1:a?", // Tenta pegar o áudio do segundo input
-			"-map", "0:a?", // Fallback: pega áudio do primeiro se o segundo falhar
-			"-c:v", "libx264",
-			"-preset", "ultrafast",
-			"-crf", "23",
-			"-c:a", "aac",
-			"-b:a", "128k",
-			"-ar", "44100", // Força sample rate padrão para evitar chiado/troca
-			"-shortest",
-			outputPath,
-		)
-
-		log.Printf("🎬 [Cutter] Iniciando FFmpeg para: %s", clipName)
-		cmd := exec.Command("ffmpeg", args...)
-		output, err := cmd.CombinedOutput()
+// runFFmpeg completa os argumentos de entrada com filtro/encode e gera o clipe
+func (c *Cutter) runFFmpeg(ctx context.Context, job *models.ClipJob, inputArgs []string, inputs int) (string, error) {
+	cs := job.Settings
+	// Pedidos antigos (anteriores ao catálogo) ganham o nome na primeira tentativa
+	if job.FileName == "" {
+		job.FileName = c.storageName(job)
+	}
+	clipName := job.FileName
+	outputPath := filepath.Join(c.StoragePath, clipName)
 
+	// Legendas do CaptionLog: sidecars sempre que pedidas, queimadas no modo "burn"
+	filter := videoFilter(cs)
+	if cs.Subtitles.Mode != "" {
+		srtPath, err := c.writeSidecars(job, clipName)
 		if err != nil {
-			log.Printf("❌ [Cutter] FFmpeg falhou: %v\nSaída: %s", err, string(output))
-		} else {
-			log.Printf("✅ [Cutter] Clipe concluído com sucesso: %s", clipName)
-			c.NotifyChan <- clipName
+			log.Printf("⚠️ [Cutter] Legendas do clipe %s não geradas: %v", clipName, err)
+		} else if cs.Subtitles.Mode == "burn" {
+			filter += "," + subtitleFilter(cs, srtPath)
 		}
-	}()
+	}
+
+	args := append(inputArgs,
+		"-filter_complex", "[0:v]"+filter+"[outv]", // Filtro no vídeo do primeiro input
+		"-map", "[outv]", // Usa o vídeo filtrado
+	)
+	if inputs > 1 {
+		args = append(args, "-map", "1:a?") // Tenta pegar o áudio do segundo input
+	}
+	args = append(args,
+		"-map", "0:a?", // Fallback: pega áudio do primeiro se o segundo falhar
+		"-c:v", "libx264",
+		"-preset", encodePreset[cs.Quality].Preset,
+		"-crf", encodePreset[cs.Quality].CRF,
+		"-c:a", "aac",
+		"-b:a", "128k",
+		"-ar", "44100", // Força sample rate padrão para evitar chiado/troca
+		"-shortest",
+		outputPath,
+	)
+
+	log.Printf("🎬 [Cutter] Iniciando FFmpeg para: %s", clipName)
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Printf("❌ [Cutter] FFmpeg falhou: %v\nSaída: %s", err, string(output))
+		return "", fmt.Errorf("ffmpeg falhou: %v", err)
+	}
+
+	log.Printf("✅ [Cutter] Clipe concluído com sucesso: %s", clipName)
+	return clipName, nil
+}
+
+// videoFilter monta o crop/escala e a marca d'água conforme a proporção
+func videoFilter(cs models.ClipSettings) string {
+	watermark := escapeDrawtext(cs.Watermark)
+	if cs.AspectRatio == "9:16" {
+		return fmt.Sprintf("crop=ih*9/16:ih,unsharp=3:3:1.5:3:3:0.5,drawtext=text='%s':fontcolor=white@0.8:fontsize=24:x=(w-tw)/2:y=60:shadowcolor=black:shadowx=2:shadowy=2", watermark)
+	}
+	return fmt.Sprintf("scale=1920:1080:force_original_aspect_ratio=decrease,pad=1920:1080:(ow-iw)/2:(oh-ih)/2,drawtext=text='%s':fontcolor=white@0.8:fontsize=32:x=(w-tw)/2:y=50:shadowcolor=black:shadowx=2:shadowy=2", watermark)
 }
@@ -0,0 +1,462 @@
+package media
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"k-lens/models"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DVR mantém, por live, um buffer rolante de segmentos HLS em disco.
+// Os cortes saem desse buffer pelo horário de parede, então "o último minuto"
+// é exatamente o que foi ao ar, mesmo que a fonte fique fora do ar depois.
+type DVR struct {
+	Root           string        // Pasta base (uma subpasta por live)
+	Retention      time.Duration // Quanto tempo de buffer manter; 0 desliga o DVR
+	SegmentSeconds int
+
+	// resolve troca a URL da live pela URL de mídia (yt-dlp)
+	resolve func(ctx context.Context, url string) ([]string, error)
+
+	mu        sync.Mutex
+	recorders map[string]*recorder
+	pins      map[string]int  // Pedidos de corte ainda pendentes que dependem do buffer
+	ended     map[string]bool // Lives paradas: o buffer some quando o último pedido terminar
+}
+
+type recorder struct {
+	sourceURL string
+	dir       string
+	cancel    context.CancelFunc
+}
+
+// Segment é um pedaço do buffer com o horário em que foi ao ar
+type Segment struct {
+	Path     string
+	Start    time.Time
+	Duration time.Duration
+}
+
+// End é o horário em que o segmento termina
+func (s Segment) End() time.Time {
+	return s.Start.Add(s.Duration)
+}
+
+// NewDVRFromEnv lê DVR_PATH (padrão ./dvr) e DVR_RETENTION (padrão 10m; "0" desliga)
+func NewDVRFromEnv(resolve func(ctx context.Context, url string) ([]string, error)) *DVR {
+	root := os.Getenv("DVR_PATH")
+	if root == "" {
+		root = "./dvr"
+	}
+
+	retention := 10 * time.Minute
+	if v := os.Getenv("DVR_RETENTION"); v != "" {
+		if v == "0" {
+			retention = 0
+		} else if d, err := time.ParseDuration(v); err == nil {
+			retention = d
+		} else {
+			log.Printf("⚠️ [DVR] DVR_RETENTION inválido (%s), usando %s", v, retention)
+		}
+	}
+
+	return &DVR{
+		Root:           root,
+		Retention:      retention,
+		SegmentSeconds: 2,
+		resolve:        resolve,
+		recorders:      make(map[string]*recorder),
+		pins:           make(map[string]int),
+		ended:          make(map[string]bool),
+	}
+}
+
+// Enabled indica se o buffer local está ligado
+func (d *DVR) Enabled() bool {
+	return d != nil && d.Retention > 0
+}
+
+// Ensure começa a gravar a live (ou reinicia, se a URL mudou). Cada gravação usa
+// uma subpasta própria: trocar a URL não apaga os segmentos que pedidos na fila ainda usam.
+func (d *DVR) Ensure(ctx context.Context, liveID, sourceURL string) {
+	if !d.Enabled() || sourceURL == "" {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if rec, ok := d.recorders[liveID]; ok {
+		if rec.sourceURL == sourceURL {
+			return
+		}
+		rec.cancel()
+	}
+	delete(d.ended, liveID)
+
+	dir := filepath.Join(d.liveDir(liveID), fmt.Sprintf("rec_%d", time.Now().UnixNano()))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Printf("❌ [DVR] Erro ao criar buffer da live %s: %v", liveID, err)
+		return
+	}
+
+	recCtx, cancel := context.WithCancel(ctx)
+	d.recorders[liveID] = &recorder{sourceURL: sourceURL, dir: dir, cancel: cancel}
+	d.pruneLocked(liveID)
+	go d.record(recCtx, liveID, sourceURL, dir)
+}
+
+// Stop encerra a gravação da live. O buffer continua legível até o último pedido
+// de corte pendente da live terminar (Release) e só então é apagado.
+func (d *DVR) Stop(liveID string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if rec, ok := d.recorders[liveID]; ok {
+		rec.cancel()
+		delete(d.recorders, liveID)
+	}
+	d.ended[liveID] = true
+	if d.pins[liveID] == 0 {
+		d.removeLocked(liveID)
+	}
+}
+
+// Recording indica se o buffer da live ainda está crescendo
+func (d *DVR) Recording(liveID string) bool {
+	if d == nil {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, ok := d.recorders[liveID]
+	return ok
+}
+
+// Hold marca um pedido de corte que vai ler o buffer da live
+func (d *DVR) Hold(liveID string) {
+	if !d.Enabled() {
+		return
+	}
+	d.mu.Lock()
+	d.pins[liveID]++
+	d.mu.Unlock()
+}
+
+// Release libera o buffer quando o pedido termina (apagando-o se a live já parou)
+func (d *DVR) Release(liveID string) {
+	if !d.Enabled() {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.pins[liveID] > 0 {
+		d.pins[liveID]--
+	}
+	if d.pins[liveID] > 0 {
+		return
+	}
+	delete(d.pins, liveID)
+	if d.ended[liveID] {
+		d.removeLocked(liveID)
+		return
+	}
+	d.pruneLocked(liveID)
+}
+
+func (d *DVR) liveDir(liveID string) string {
+	return filepath.Join(d.Root, sanitizeDirName(liveID))
+}
+
+func (d *DVR) removeLocked(liveID string) {
+	delete(d.ended, liveID)
+	os.RemoveAll(d.liveDir(liveID))
+}
+
+// pruneLocked apaga o que já saiu da retenção: gravações antigas (URL trocada) e
+// segmentos velhos da gravação atual. O ffmpeg não apaga nada sozinho; enquanto houver
+// pedido de corte pendente (Hold) o buffer fica inteiro.
+func (d *DVR) pruneLocked(liveID string) {
+	if d.pins[liveID] > 0 {
+		return
+	}
+	current := ""
+	if rec, ok := d.recorders[liveID]; ok {
+		current = rec.dir
+	}
+	cutoff := time.Now().Add(-d.Retention)
+	dirs, _ := filepath.Glob(filepath.Join(d.liveDir(liveID), "rec_*"))
+	for _, dir := range dirs {
+		if dir == current {
+			segments, _ := readPlaylist(filepath.Join(dir, "index.m3u8"))
+			for _, s := range segments {
+				if s.End().Before(cutoff) {
+					os.Remove(s.Path)
+				}
+			}
+			continue
+		}
+		info, err := os.Stat(filepath.Join(dir, "index.m3u8"))
+		if err != nil || info.ModTime().Before(cutoff) {
+			os.RemoveAll(dir)
+		}
+	}
+}
+
+// prune é o pruneLocked com o lock, chamado periodicamente durante a gravação
+func (d *DVR) prune(liveID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pruneLocked(liveID)
+}
+
+// record mantém o ffmpeg gravando, reconectando com backoff se a fonte cair
+func (d *DVR) record(ctx context.Context, liveID, sourceURL, dir string) {
+	go func() {
+		ticker := time.NewTicker(time.Duration(d.SegmentSeconds) * 15 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				d.prune(liveID)
+			}
+		}
+	}()
+
+	backoff := 2 * time.Second
+	for ctx.Err() == nil {
+		urls, err := d.resolve(ctx, sourceURL)
+		if err == nil && len(urls) > 0 {
+			log.Printf("📼 [DVR] Gravando live %s em %s (retenção %s)", liveID, dir, d.Retention)
+			started := time.Now()
+			err = d.runSegmenter(ctx, urls[0], dir)
+			if time.Since(started) > time.Minute {
+				backoff = 2 * time.Second
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("⚠️ [DVR] Gravação da live %s interrompida (%v), tentando de novo em %s", liveID, err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+// runSegmenter grava sem delete_segments e com a playlist completa: quem apaga os
+// segmentos é o pruneLocked, que respeita os pedidos de corte pendentes
+func (d *DVR) runSegmenter(ctx context.Context, mediaURL, dir string) error {
+	args := []string{
+		"-y", "-loglevel", "error",
+		"-reconnect", "1", "-reconnect_at_eof", "1", "-reconnect_streamed", "1", "-reconnect_delay_max", "5",
+		"-i", strings.TrimSpace(mediaURL),
+		"-map", "0:v?", "-map", "0:a?",
+		"-c", "copy",
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(d.SegmentSeconds),
+		"-hls_list_size", "0",
+		"-hls_flags", "program_date_time+append_list",
+		"-hls_segment_filename", filepath.Join(dir, "seg_%08d.ts"),
+		filepath.Join(dir, "index.m3u8"),
+	}
+	out, err := exec.CommandContext(ctx, "ffmpeg", args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Segments lê as playlists da live (gravação atual e anteriores ainda no disco)
+// e devolve os segmentos em ordem de exibição
+func (d *DVR) Segments(liveID string) ([]Segment, error) {
+	if d == nil {
+		return nil, fmt.Errorf("DVR desligado")
+	}
+	playlists, _ := filepath.Glob(filepath.Join(d.liveDir(liveID), "rec_*", "index.m3u8"))
+	if len(playlists) == 0 {
+		return nil, fmt.Errorf("live %s não tem buffer gravado", liveID)
+	}
+
+	var segments []Segment
+	for _, playlist := range playlists {
+		found, err := readPlaylist(playlist)
+		if err != nil {
+			return nil, err
+		}
+		segments = append(segments, found...)
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].Start.Before(segments[j].Start) })
+	return segments, nil
+}
+
+// readPlaylist lê uma playlist HLS com PROGRAM-DATE-TIME
+func readPlaylist(path string) ([]Segment, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dir := filepath.Dir(path)
+
+	var (
+		segments []Segment
+		nextPDT  time.Time
+		nextDur  time.Duration
+	)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"):
+			nextPDT, _ = parseProgramDateTime(strings.TrimPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"))
+		case strings.HasPrefix(line, "#EXTINF:"):
+			v := strings.TrimSuffix(strings.SplitN(strings.TrimPrefix(line, "#EXTINF:"), ",", 2)[0], ",")
+			secs, _ := strconv.ParseFloat(v, 64)
+			nextDur = time.Duration(secs * float64(time.Second))
+		case line != "" && !strings.HasPrefix(line, "#"):
+			start := nextPDT
+			if start.IsZero() && len(segments) > 0 {
+				start = segments[len(segments)-1].End()
+			}
+			// Segmentos já apagados pela retenção continuam na playlist: ficam de fora
+			if _, err := os.Stat(filepath.Join(dir, line)); !start.IsZero() && err == nil {
+				segments = append(segments, Segment{Path: filepath.Join(dir, line), Start: start, Duration: nextDur})
+			}
+			nextPDT, nextDur = time.Time{}, 0
+		}
+	}
+	return segments, scanner.Err()
+}
+
+// Window seleciona os segmentos que cobrem [from, to]. Retorna false se o buffer
+// ainda não chegou em "to" (erro se "from" já saiu da retenção). Com a gravação
+// parada o buffer não cresce mais: o corte sai com o que houver.
+func (d *DVR) Window(liveID string, from, to time.Time) ([]Segment, bool, error) {
+	segments, err := d.Segments(liveID)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(segments) == 0 {
+		return nil, false, nil
+	}
+	if segments[0].Start.After(from) {
+		return nil, false, fmt.Errorf("trecho pedido já saiu do buffer (início em %s)", segments[0].Start.Format(time.RFC3339))
+	}
+	if segments[len(segments)-1].End().Before(to) && d.Recording(liveID) {
+		return nil, false, nil
+	}
+
+	var picked []Segment
+	for _, s := range segments {
+		if s.End().After(from) && s.Start.Before(to) {
+			picked = append(picked, s)
+		}
+	}
+	if len(picked) == 0 && !d.Recording(liveID) {
+		return nil, false, fmt.Errorf("trecho pedido não foi gravado")
+	}
+	return picked, len(picked) > 0, nil
+}
+
+func parseProgramDateTime(v string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02T15:04:05.999-0700", time.RFC3339Nano} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("PROGRAM-DATE-TIME inválido: %s", v)
+}
+
+func sanitizeDirName(liveID string) string {
+	name := unsafeLabel.ReplaceAllString(liveID, "_")
+	if name == "" {
+		return "live"
+	}
+	return name
+}
+
+// StartRecording liga o DVR da live, vinculado ao ciclo de vida do Cutter
+func (c *Cutter) StartRecording(liveID, sourceURL string) {
+	c.DVR.Ensure(c.ctx, liveID, sourceURL)
+}
+
+// dvrGrace é quanto o buffer pode atrasar em relação ao fim do trecho antes de desistir
+const dvrGrace = 30 * time.Second
+
+// dvrWait indica que o buffer ainda não cobre o trecho: o pedido é reagendado para until
+// em vez de prender um worker esperando
+type dvrWait struct {
+	until time.Time
+}
+
+func (e *dvrWait) Error() string {
+	return "buffer ainda não alcançou o fim do trecho (nova tentativa às " + e.until.Format("15:04:05") + ")"
+}
+
+// encodeFromDVR corta pelos segmentos locais quando o buffer já cobre o trecho do pedido
+func (c *Cutter) encodeFromDVR(ctx context.Context, job *models.ClipJob) (string, error) {
+	cs := job.Settings
+	from := job.TriggeredAt.Add(-time.Duration(cs.PreRoll) * time.Second)
+	to := from.Add(time.Duration(cs.Duration) * time.Second)
+
+	// O fim do trecho pode estar no futuro: o pedido volta para a fila até o buffer alcançá-lo
+	segments, ok, err := c.DVR.Window(job.LiveID, from, to)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		if time.Now().After(to.Add(dvrGrace)) {
+			return "", fmt.Errorf("buffer não alcançou o fim do trecho pedido")
+		}
+		// Buffer atrasado (to já passou): tenta de novo em um segmento, sem girar em falso
+		until := to
+		if now := time.Now(); until.Before(now) {
+			until = now
+		}
+		return "", &dvrWait{until: until.Add(time.Duration(c.DVR.SegmentSeconds) * time.Second)}
+	}
+
+	list, err := os.CreateTemp("", "klens_dvr_*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(list.Name())
+	for _, s := range segments {
+		abs, _ := filepath.Abs(s.Path)
+		fmt.Fprintf(list, "file '%s'\n", strings.ReplaceAll(abs, "'", `'\''`))
+	}
+	list.Close()
+
+	c.setStatus(job, models.ClipJobEncoding)
+	offset := from.Sub(segments[0].Start).Seconds()
+	if offset < 0 {
+		offset = 0
+	}
+	// -ss antes do -i: os timestamps do clipe começam em zero (legendas queimadas alinham)
+	args := []string{
+		"-y",
+		"-ss", fmt.Sprintf("%.3f", offset),
+		"-f", "concat", "-safe", "0", "-i", list.Name(),
+		"-t", fmt.Sprintf("%d", cs.Duration),
+	}
+	return c.runFFmpeg(ctx, job, args, 1)
+}
@@ -0,0 +1,89 @@
+package media
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writePlaylist grava uma gravação falsa com n segmentos de 2s a partir de start
+func writePlaylist(t *testing.T, dir string, start time.Time, n int) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	playlist := "#EXTM3U\n"
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("seg_%08d.ts", i)
+		os.WriteFile(filepath.Join(dir, name), []byte("ts"), 0644)
+		playlist += fmt.Sprintf("#EXT-X-PROGRAM-DATE-TIME:%s\n#EXTINF:2.000000,\n%s\n",
+			start.Add(time.Duration(i)*2*time.Second).Format("2006-01-02T15:04:05.000-0700"), name)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.m3u8"), []byte(playlist), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDVRBufferOutlivesStopWhileJobsPending(t *testing.T) {
+	d := &DVR{Root: t.TempDir(), Retention: 10 * time.Minute, SegmentSeconds: 2,
+		recorders: map[string]*recorder{}, pins: map[string]int{}, ended: map[string]bool{}}
+	start := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	// Duas gravações (URL trocada no meio): as duas continuam legíveis
+	writePlaylist(t, filepath.Join(d.liveDir("7"), "rec_1"), start, 5)
+	writePlaylist(t, filepath.Join(d.liveDir("7"), "rec_2"), start.Add(10*time.Second), 5)
+	segments, err := d.Segments("7")
+	if err != nil || len(segments) != 10 {
+		t.Fatalf("segmentos: %d, %v", len(segments), err)
+	}
+
+	// Live encerrada com um corte pendente: o buffer fica até o corte terminar
+	d.Hold("7")
+	d.Stop("7")
+	picked, ok, err := d.Window("7", start.Add(15*time.Second), start.Add(40*time.Second))
+	if err != nil || !ok || len(picked) != 3 {
+		t.Fatalf("janela depois do stop: %d, %v, %v", len(picked), ok, err)
+	}
+
+	d.Release("7")
+	if _, err := os.Stat(d.liveDir("7")); !os.IsNotExist(err) {
+		t.Fatal("buffer deveria ser apagado depois do último pedido")
+	}
+}
+
+func TestDVRPruneKeepsPinnedSegments(t *testing.T) {
+	d := &DVR{Root: t.TempDir(), Retention: time.Minute, SegmentSeconds: 2,
+		recorders: map[string]*recorder{}, pins: map[string]int{}, ended: map[string]bool{}}
+	dir := filepath.Join(d.liveDir("7"), "rec_1")
+	d.recorders["7"] = &recorder{dir: dir, cancel: func() {}}
+	// 5 segmentos que saíram da retenção seguidos de 5 recentes
+	writePlaylist(t, dir, time.Now().Add(-2*time.Minute).Truncate(time.Second), 5)
+	recent := time.Now().Add(-5 * time.Second).Truncate(time.Second)
+	f, _ := os.OpenFile(filepath.Join(dir, "index.m3u8"), os.O_APPEND|os.O_WRONLY, 0644)
+	for i := 5; i < 10; i++ {
+		name := fmt.Sprintf("seg_%08d.ts", i)
+		os.WriteFile(filepath.Join(dir, name), []byte("ts"), 0644)
+		fmt.Fprintf(f, "#EXT-X-PROGRAM-DATE-TIME:%s\n#EXTINF:2.000000,\n%s\n",
+			recent.Add(time.Duration(i-5)*2*time.Second).Format("2006-01-02T15:04:05.000-0700"), name)
+	}
+	f.Close()
+
+	// Com um corte pendente nada é apagado
+	d.Hold("7")
+	d.prune("7")
+	if segments, _ := d.Segments("7"); len(segments) != 10 {
+		t.Fatalf("buffer preso perdeu segmentos: %d", len(segments))
+	}
+
+	// Sem pedidos, os segmentos fora da retenção somem do disco e da leitura
+	d.Release("7")
+	segments, _ := d.Segments("7")
+	if len(segments) != 5 {
+		t.Fatalf("esperava 5 segmentos depois da limpeza, há %d", len(segments))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "seg_00000000.ts")); !os.IsNotExist(err) {
+		t.Fatal("segmento velho deveria ter sido apagado")
+	}
+}
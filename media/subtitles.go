@@ -0,0 +1,159 @@
+package media
+
+import (
+	"fmt"
+	"k-lens/db"
+	"k-lens/models"
+	"k-lens/subtitle"
+	"k-lens/translate"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var safeFont = regexp.MustCompile(`^[A-Za-z0-9 _-]{1,40}$`)
+
+// normalizeSubtitles completa o estilo com padrões (fonte, posição)
+func normalizeSubtitles(st models.SubtitleStyle) models.SubtitleStyle {
+	if st.Mode == "none" {
+		st.Mode = ""
+	}
+	if st.Font == "" {
+		st.Font = "Arial"
+	}
+	if st.Position == "" {
+		st.Position = "bottom"
+	}
+	if lang, ok := translate.NormalizeLanguage(st.Language); ok {
+		st.Language = lang
+	} else if st.Language == "" {
+		st.Language = translate.DefaultLanguage
+	}
+	return st
+}
+
+func validateSubtitles(st models.SubtitleStyle) error {
+	switch st.Mode {
+	case "", "sidecar", "burn":
+	default:
+		return fmt.Errorf("modo de legenda não suportado: %s", st.Mode)
+	}
+	switch st.Position {
+	case "bottom", "middle", "top":
+	default:
+		return fmt.Errorf("posição de legenda não suportada: %s", st.Position)
+	}
+	if st.Size != 0 && (st.Size < 8 || st.Size > 96) {
+		return fmt.Errorf("tamanho de legenda fora do intervalo (8-96): %d", st.Size)
+	}
+	if !safeFont.MatchString(st.Font) {
+		return fmt.Errorf("nome de fonte inválido: %s", st.Font)
+	}
+	if _, ok := translate.NormalizeLanguage(st.Language); !ok {
+		return fmt.Errorf("idioma de legenda não suportado: %s", st.Language)
+	}
+	return nil
+}
+
+// writeSidecars gera <clipe>.srt e <clipe>.vtt com as legendas que caem na janela
+// do corte, já re-cronometradas para o início do clipe. Retorna o caminho do SRT.
+func (c *Cutter) writeSidecars(job *models.ClipJob, clipName string) (string, error) {
+	cues, err := clipCues(job)
+	if err != nil {
+		return "", err
+	}
+
+	base := strings.TrimSuffix(filepath.Join(c.StoragePath, clipName), filepath.Ext(clipName))
+	srtPath := base + ".srt"
+
+	srt, err := os.Create(srtPath)
+	if err != nil {
+		return "", err
+	}
+	defer srt.Close()
+	if err := subtitle.WriteSRT(srt, cues); err != nil {
+		return "", err
+	}
+
+	vtt, err := os.Create(base + ".vtt")
+	if err != nil {
+		return "", err
+	}
+	defer vtt.Close()
+	if err := subtitle.WriteVTT(vtt, cues); err != nil {
+		return "", err
+	}
+
+	return srtPath, nil
+}
+
+// clipCues busca no CaptionLog as legendas que se sobrepõem à janela do corte
+func clipCues(job *models.ClipJob) ([]subtitle.Cue, error) {
+	if db.DB == nil {
+		return nil, nil
+	}
+	liveID, err := strconv.ParseUint(job.LiveID, 10, 32)
+	if err != nil {
+		return nil, nil
+	}
+
+	from := time.Duration(job.Timestamp)*time.Millisecond - time.Duration(job.Settings.PreRoll)*time.Second
+	if from < 0 {
+		from = 0
+	}
+	to := from + time.Duration(job.Settings.Duration)*time.Second
+
+	var captions []models.CaptionLog
+	lang := job.Settings.Subtitles.Language
+	if lang == "" {
+		lang = translate.DefaultLanguage
+	}
+	err = db.DB.Where("live_archive_id = ? AND language = ? AND timestamp >= ? AND timestamp < ?",
+		liveID, lang, (from - subtitle.DefaultMaxDuration).Milliseconds(), to.Milliseconds()).
+		Order("timestamp").Find(&captions).Error
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar legendas do corte: %v", err)
+	}
+	if len(captions) > 0 {
+		ids := make([]uint, len(captions))
+		for i, c := range captions {
+			ids[i] = c.ID
+		}
+		var revisions []models.CaptionRevision
+		if err := db.DB.Where("caption_log_id IN ?", ids).Find(&revisions).Error; err != nil {
+			return nil, fmt.Errorf("erro ao buscar correções do corte: %v", err)
+		}
+		captions = subtitle.ApplyRevisions(captions, revisions)
+	}
+
+	return subtitle.Retime(subtitle.FromCaptions(captions, subtitle.DefaultMaxDuration), from, to), nil
+}
+
+// subtitleFilter queima o SRT no vídeo com o estilo do pedido.
+// Tamanho e margem padrão mudam com a proporção: no 9:16 a legenda sobe para fugir da UI dos Shorts/Reels.
+func subtitleFilter(cs models.ClipSettings, srtPath string) string {
+	st := cs.Subtitles
+	size, marginV := 18, 20
+	if cs.AspectRatio == "9:16" {
+		size, marginV = 14, 60
+	}
+	if st.Size != 0 {
+		size = st.Size
+	}
+
+	// Alinhamento ASS (teclado numérico): 2 = embaixo, 5 = meio, 8 = em cima
+	alignment := 2
+	switch st.Position {
+	case "middle":
+		alignment = 5
+	case "top":
+		alignment = 8
+	}
+
+	path := strings.NewReplacer(`\`, `/`, `:`, `\:`, `'`, `\'`).Replace(filepath.ToSlash(srtPath))
+	return fmt.Sprintf("subtitles=filename='%s':force_style='FontName=%s,FontSize=%d,Alignment=%d,MarginV=%d,Outline=2,Shadow=1'",
+		path, st.Font, size, alignment, marginV)
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+// CaptionRevision guarda cada correção feita por um moderador numa legenda.
+// O CaptionLog fica com o texto original do tradutor; a revisão mais alta é a que vale.
+type CaptionRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	CaptionLogID  uint   `gorm:"uniqueIndex:idx_caption_revision;not null" json:"caption_log_id"`
+	Revision      int    `gorm:"uniqueIndex:idx_caption_revision" json:"revision"` // 1, 2, 3... (0 é o original)
+	CaptionID     string `gorm:"index" json:"caption_id"`
+	LiveArchiveID uint   `gorm:"index" json:"live_archive_id"`
+	Language      string `json:"language"`
+
+	Text     string `json:"text"`
+	Speaker  string `json:"speaker"`
+	Deleted  bool   `gorm:"default:false" json:"deleted"` // Legenda retirada
+	AuthorID uint   `json:"author_id"`
+}
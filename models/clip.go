@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+// Estados de um clipe no catálogo
+const (
+	ClipProcessing = "processing" // Pedido na fila ou renderizando
+	ClipReady      = "ready"
+	ClipFailed     = "failed"
+)
+
+// Clip é o registro de um corte no catálogo (um por ClipJob). O arquivo fica em
+// ./recordings/<FileName>; Title é o nome exibido e pode ser trocado sem mexer no arquivo.
+type Clip struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	LiveArchiveID uint   `gorm:"index" json:"live_id"`
+	ClipJobID     uint   `gorm:"uniqueIndex" json:"job_id"`
+	FileName      string `gorm:"uniqueIndex;not null" json:"file_name"` // Nome único no storage
+	Title         string `json:"title"`
+
+	// Trecho da live (milissegundos desde o início) e formato
+	StartMs     int64  `json:"start_ms"`
+	EndMs       int64  `json:"end_ms"`
+	Duration    int    `json:"duration"` // Segundos
+	AspectRatio string `gorm:"index" json:"aspect_ratio"`
+	Label       string `gorm:"index" json:"label"`
+	Reason      string `json:"reason,omitempty"` // Regras de destaque que dispararam o corte
+
+	FileSize int64  `json:"file_size"`
+	Checksum string `json:"checksum,omitempty"` // SHA-256 do mp4
+	Status   string `gorm:"index;default:processing" json:"status"`
+}
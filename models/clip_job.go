@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// Estados de um pedido de corte
+const (
+	ClipJobQueued    = "queued"
+	ClipJobResolving = "resolving" // yt-dlp buscando as URLs do stream
+	ClipJobEncoding  = "encoding"  // ffmpeg cortando e renderizando
+	ClipJobDone      = "done"
+	ClipJobFailed    = "failed"
+)
+
+type ClipJob struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	LiveID      string    `gorm:"index" json:"live_id"`
+	SourceURL   string    `json:"source_url"`
+	Timestamp   float64   `json:"timestamp"`    // Milissegundos desde o início da live
+	TriggeredAt time.Time `json:"triggered_at"` // Horário de parede do gatilho (corte via DVR)
+	Label       string    `json:"label"`
+	Reason      string    `json:"reason,omitempty"` // Regras que dispararam o corte automático
+
+	// Configuração congelada no momento do pedido
+	Settings ClipSettings `gorm:"embedded;embeddedPrefix:clip_" json:"settings"`
+
+	Status        string    `gorm:"index;default:queued" json:"status"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"last_error,omitempty"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	FileName      string    `json:"file_name,omitempty"`
+}
+
+// ActiveClipJobStatuses são os estados de pedido que ainda precisam ser processados
+// (retomados no start do Cutter)
+var ActiveClipJobStatuses = []string{ClipJobQueued, ClipJobResolving, ClipJobEncoding}
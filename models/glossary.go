@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// GlossaryTerm é um termo do fandom com grafia obrigatória nas legendas
+// (nomes de membros, nomes artísticos, nome do fandom, programas).
+type GlossaryTerm struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Artist restringe o termo às lives com esse IdolName (vazio = todas as lives)
+	Artist         string `gorm:"uniqueIndex:idx_glossary_term;index" json:"artist"`
+	Source         string `gorm:"uniqueIndex:idx_glossary_term;not null" json:"source"` // Forma original, ex: "지민"
+	Translation    string `json:"translation"`                                          // Grafia preferida, ex: "Jimin"
+	Variants       string `json:"variants"`                                             // Grafias erradas a corrigir, separadas por vírgula
+	DoNotTranslate bool   `gorm:"default:false" json:"do_not_translate"`                // Mantém o termo como está (ex: "ARMY")
+	Language       string `gorm:"uniqueIndex:idx_glossary_term" json:"language"`        // Idioma de destino (vazio = todos)
+	Notes          string `json:"notes"`
+	UpdatedBy      uint   `json:"updated_by"`
+}
+
+// VariantList devolve as grafias alternativas em ordem
+func (t GlossaryTerm) VariantList() []string {
+	return splitList(t.Variants)
+}
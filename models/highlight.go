@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+)
+
+// Tipos de regra de destaque
+const (
+	HighlightKeyword     = "keyword"      // Palavras na tradução ou na transcrição coreana
+	HighlightRegex       = "regex"        // Expressão regular na tradução ou na transcrição
+	HighlightLoudness    = "loudness"     // Pico de volume (dB acima da média recente)
+	HighlightCaptionRate = "caption_rate" // Rajada de falas (legendas na janela)
+	HighlightReactions   = "reactions"    // Pico de reações do público (reações na janela)
+)
+
+// Campo de texto olhado pelas regras keyword/regex
+const (
+	HighlightFieldTranslation = "translation"
+	HighlightFieldTranscript  = "transcript"
+)
+
+// HighlightRule é uma regra de corte automático da live.
+// Cada regra que dispara soma Weight ao placar; o corte sai quando o placar
+// alcança LiveArchive.HighlightThreshold.
+type HighlightRule struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	LiveArchiveID uint   `gorm:"index;not null" json:"live_id"`
+	Name          string `json:"name"`
+	Kind          string `gorm:"not null" json:"kind"`
+	Field         string `json:"field,omitempty"`   // keyword/regex: translation ou transcript
+	Pattern       string `json:"pattern,omitempty"` // keyword: palavras separadas por vírgula; regex: a expressão
+
+	Weight    float64 `json:"weight"`
+	Threshold float64 `json:"threshold"` // Ocorrências (texto), dB (volume), legendas ou reações na janela
+	Window    int     `json:"window"`    // Segundos (caption_rate/reactions)
+	Cooldown  int     `json:"cooldown"`  // Segundos sem disparar de novo depois de um corte
+	Disabled  bool    `json:"disabled"`
+}
+
+// Keywords devolve as palavras de uma regra keyword
+func (r HighlightRule) Keywords() []string {
+	return splitList(r.Pattern)
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// AccessInvite registra um token de convite emitido por um host.
+// O token em si é assinado (HMAC); esta linha existe para permitir revogação.
+type AccessInvite struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	TokenID       string     `gorm:"uniqueIndex;not null" json:"token_id"`
+	LiveArchiveID uint       `gorm:"index;not null" json:"live_archive_id"`
+	CreatedBy     uint       `json:"created_by"`
+	Role          string     `json:"role"`
+	ExpiresAt     time.Time  `json:"expires_at"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+}
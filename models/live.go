@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -15,13 +16,98 @@ type LiveArchive struct {
 	Title     string `json:"title"`
 	IdolName  string `json:"idol_name"`
 	Platform  string `json:"platform"`   // Ex: Weverse, YouTube
+	SourceURL string `json:"source_url"` // Link da live original (yt-dlp/DVR)
 	VideoPath string `json:"video_path"` // Caminho do arquivo para o FFmpeg
+
+	// Ciclo de vida: horários gravados pelo servidor, compartilhados por todas as conexões
+	Status    string     `gorm:"index;default:created" json:"status"` // created, live, ended
+	StartedAt *time.Time `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+
+	// Membros que podem aparecer na live, separados por vírgula (ex: "RM,Jin,Suga")
+	Roster string `json:"roster"`
+
+	// Idiomas de legenda, separados por vírgula; o primeiro é o principal (ex: "pt-BR,en,es")
+	Languages string `gorm:"default:pt-BR" json:"languages"`
+
+	// Segundos que a legenda da IA fica retida para revisão antes de ir ao ar (0 = sem revisão)
+	ReviewDelay int `json:"review_delay"`
+
+	// Cortes automáticos: placar mínimo das regras (HighlightRule) e modo de teste (só loga)
+	HighlightThreshold float64 `gorm:"default:1" json:"highlight_threshold"`
+	HighlightDryRun    bool    `json:"highlight_dry_run"`
+
+	// Padrões de corte da live (ajustados pelo update_config do studio)
+	ClipDefaults ClipSettings `gorm:"embedded;embeddedPrefix:clip_" json:"clip_defaults"`
+}
+
+// LanguageList devolve os idiomas de legenda da live em ordem
+func (l LiveArchive) LanguageList() []string {
+	return splitList(l.Languages)
+}
+
+// RosterList devolve os nomes dos membros da live
+func (l LiveArchive) RosterList() []string {
+	return splitList(l.Roster)
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// Estados de uma LiveArchive
+const (
+	LiveCreated = "created"
+	LiveOn      = "live"
+	LiveEnded   = "ended"
+)
+
+// ClipSettings descreve como um corte deve ser renderizado
+type ClipSettings struct {
+	Duration    int    `json:"duration"`     // Segundos de clipe
+	PreRoll     int    `json:"pre_roll"`     // Segundos antes do gatilho
+	AspectRatio string `json:"aspect_ratio"` // "9:16" ou "16:9"
+	Watermark   string `json:"watermark"`
+	Quality     string `json:"quality"` // "low", "medium" ou "high"
+
+	Subtitles SubtitleStyle `gorm:"embedded;embeddedPrefix:sub_" json:"subtitles"`
+}
+
+// SubtitleStyle controla as legendas geradas a partir do CaptionLog no clipe
+type SubtitleStyle struct {
+	Mode     string `json:"mode"`     // "" (sem legenda), "sidecar" (.srt/.vtt) ou "burn" (queimada + sidecar)
+	Font     string `json:"font"`     // Ex: "Arial"
+	Size     int    `json:"size"`     // 0 = padrão da proporção
+	Position string `json:"position"` // "bottom", "middle" ou "top"
+	Language string `json:"language"` // Idioma das legendas do clipe (padrão pt-BR)
 }
 
 type CaptionLog struct {
 	ID            uint   `gorm:"primaryKey" json:"id"`
+	CaptionID     string `gorm:"index" json:"caption_id"` // Mesmo ID das mensagens caption_partial/caption_final
 	LiveArchiveID uint   `json:"live_archive_id"`
 	Timestamp     int64  `json:"timestamp"` // Milissegundos desde o início da live
 	Text          string `json:"text"`      // Tradução da IA
+	Language      string `gorm:"index;default:pt-BR" json:"language"`
+	Speaker       string `json:"speaker"` // Membro que está falando (vazio = desconhecido)
 	IsVipOnly     bool   `gorm:"default:false" json:"is_vip_only"`
 }
+
+// Display é o texto como o público vê: "[Jimin] ..." quando o membro é conhecido
+func (c CaptionLog) Display() string {
+	return WithSpeaker(c.Speaker, c.Text)
+}
+
+// WithSpeaker prefixa o nome do membro na legenda
+func WithSpeaker(speaker, text string) string {
+	if speaker == "" {
+		return text
+	}
+	return "[" + speaker + "] " + text
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// Papéis de um usuário dentro de uma live
+const (
+	RoleHost      = "host"      // Envia áudio e muda configuração
+	RoleModerator = "moderator" // Corta e edita legendas
+	RoleViewer    = "viewer"    // Só recebe
+)
+
+// LiveMember liga um usuário a uma live com um papel.
+// Quem não tem linha aqui entra como viewer.
+type LiveMember struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	LiveArchiveID uint   `gorm:"uniqueIndex:idx_live_member;not null" json:"live_archive_id"`
+	UserID        uint   `gorm:"uniqueIndex:idx_live_member;not null" json:"user_id"`
+	Role          string `gorm:"not null;default:viewer" json:"role"`
+}
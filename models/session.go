@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Session é a sessão server-side criada após o login com Google.
+// O cookie carrega só o token; aqui fica o hash dele.
+type Session struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	TokenHash string    `gorm:"uniqueIndex;not null" json:"-"`
+	UserID    uint      `gorm:"index;not null" json:"user_id"`
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+// Estados de assinatura registrados no histórico
+const (
+	SubscriptionActive    = "active"
+	SubscriptionPastDue   = "past_due"
+	SubscriptionCancelled = "cancelled"
+)
+
+// SubscriptionHistory guarda cada evento de cobrança recebido.
+// EventID único garante idempotência: o mesmo webhook reenviado não é aplicado duas vezes.
+type SubscriptionHistory struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	EventID        string    `gorm:"uniqueIndex;not null" json:"event_id"`
+	EventType      string    `json:"event_type"`
+	UserID         uint      `gorm:"index" json:"user_id"`
+	SubscriptionID string    `gorm:"index" json:"subscription_id"`
+	Status         string    `json:"status"`
+	PeriodEnd      time.Time `json:"period_end"`
+	OccurredAt     time.Time `gorm:"index" json:"occurred_at"`
+	Applied        bool      `json:"applied"` // false quando o evento chegou fora de ordem
+}
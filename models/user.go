@@ -18,6 +18,11 @@ type User struct {
 	Avatar   string `json:"avatar"`
 
 	// Nível de acesso para monetização
-	IsVIP          bool   `gorm:"default:false" json:"is_vip"`
-	SubscriptionID string `json:"subscription_id"` // ID do Stripe/MercadoPago
+	IsVIP          bool       `gorm:"default:false" json:"is_vip"`
+	SubscriptionID string     `gorm:"index" json:"subscription_id"`      // ID do Stripe/MercadoPago
+	VIPUntil       *time.Time `gorm:"column:vip_until" json:"vip_until"` // Fim do período pago + carência
+
+	// Equipe de tradução: pode editar o glossário do fandom. Concedido por outro tradutor
+	// (PUT /api/translators); a equipe inicial vem da variável TRANSLATORS
+	IsTranslator bool `gorm:"default:false" json:"is_translator"`
 }
@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Limit é a configuração de um token bucket: Rate fichas por segundo, até Burst acumuladas
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Disabled indica limite desligado (tudo passa)
+func (l Limit) Disabled() bool {
+	return l.Rate <= 0 || l.Burst <= 0
+}
+
+// ParseLimit lê "<n>/<s|m|h>[:<burst>]", ex: "20/s", "10/m", "10/m:3". "off" ou "0" desliga.
+// Sem burst explícito, o burst é o próprio n (um período inteiro de folga).
+func ParseLimit(v string) (Limit, error) {
+	v = strings.TrimSpace(v)
+	if v == "" || v == "0" || v == "off" {
+		return Limit{}, nil
+	}
+
+	spec, burstStr, hasBurst := strings.Cut(v, ":")
+	nStr, unit, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("limite inválido %q (use n/s, n/m ou n/h)", v)
+	}
+	n, err := strconv.ParseFloat(nStr, 64)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("limite inválido %q", v)
+	}
+
+	var period time.Duration
+	switch unit {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("unidade inválida em %q (s, m ou h)", v)
+	}
+
+	burst := int(math.Ceil(n))
+	if hasBurst {
+		if burst, err = strconv.Atoi(burstStr); err != nil || burst <= 0 {
+			return Limit{}, fmt.Errorf("burst inválido em %q", v)
+		}
+	}
+	return Limit{Rate: n / period.Seconds(), Burst: burst}, nil
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter mantém um bucket por chave (usuário, IP...) e conta permitidos/negados
+type Limiter struct {
+	Name  string
+	Limit Limit
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	sweep   time.Time
+
+	allowed atomic.Uint64
+	denied  atomic.Uint64
+
+	now func() time.Time
+}
+
+// New cria o limiter e o registra para as métricas
+func New(name string, limit Limit) *Limiter {
+	l := &Limiter{Name: name, Limit: limit, buckets: make(map[string]*bucket), now: time.Now}
+	register(l)
+	return l
+}
+
+// Allow consome uma ficha da chave. Chave vazia ou limite desligado sempre passa.
+func (l *Limiter) Allow(key string) bool {
+	if l == nil || key == "" || l.Limit.Disabled() {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Limit.Burst), last: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens = math.Min(float64(l.Limit.Burst), b.tokens+now.Sub(b.last).Seconds()*l.Limit.Rate)
+		b.last = now
+	}
+	l.gc(now)
+
+	if b.tokens < 1 {
+		l.denied.Add(1)
+		return false
+	}
+	b.tokens--
+	l.allowed.Add(1)
+	return true
+}
+
+// gc descarta de tempos em tempos os buckets que já se encheram (equivalem a um bucket novo)
+func (l *Limiter) gc(now time.Time) {
+	if now.Sub(l.sweep) < time.Minute {
+		return
+	}
+	l.sweep = now
+	refill := time.Duration(float64(l.Limit.Burst) / l.Limit.Rate * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > refill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Keys devolve quantas chaves estão sendo acompanhadas
+func (l *Limiter) Keys() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{"20/s", Limit{Rate: 20, Burst: 20}, false},
+		{"10/m", Limit{Rate: 10.0 / 60, Burst: 10}, false},
+		{"10/m:3", Limit{Rate: 10.0 / 60, Burst: 3}, false},
+		{"1.5/s", Limit{Rate: 1.5, Burst: 2}, false},
+		{" 36/h ", Limit{Rate: 0.01, Burst: 36}, false},
+		{"off", Limit{}, false},
+		{"0", Limit{}, false},
+		{"", Limit{}, false},
+		{"10", Limit{}, true},
+		{"10/d", Limit{}, true},
+		{"-1/s", Limit{}, true},
+		{"abc/s", Limit{}, true},
+		{"10/s:0", Limit{}, true},
+		{"10/s:x", Limit{}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseLimit(tc.in)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("erro %v, esperava erro: %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Fatalf("limite %+v, esperava %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// testLimiter cria um limiter com relógio controlado pelo teste (fora do registro de métricas)
+func testLimiter(limit Limit) (*Limiter, *time.Time) {
+	now := time.Unix(1_800_000_000, 0)
+	l := &Limiter{Name: "teste", Limit: limit, buckets: map[string]*bucket{}}
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestAllowBurstThenRefill(t *testing.T) {
+	l, now := testLimiter(Limit{Rate: 2, Burst: 3})
+
+	// O burst passa de uma vez; a próxima espera a recarga
+	for i := 0; i < 3; i++ {
+		if !l.Allow("u1") {
+			t.Fatalf("ficha %d do burst recusada", i+1)
+		}
+	}
+	if l.Allow("u1") {
+		t.Fatal("burst esgotado deveria recusar")
+	}
+	if !l.Allow("u2") {
+		t.Fatal("cada chave tem o próprio bucket")
+	}
+
+	// 2 fichas/s: meio segundo devolve uma ficha
+	*now = now.Add(500 * time.Millisecond)
+	if !l.Allow("u1") || l.Allow("u1") {
+		t.Fatal("meio segundo deveria recarregar exatamente uma ficha")
+	}
+
+	// A recarga para no burst
+	*now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		l.Allow("u1")
+	}
+	if l.Allow("u1") {
+		t.Fatal("recarga não pode passar do burst")
+	}
+
+	if l.allowed.Load() != 8 || l.denied.Load() != 3 {
+		t.Fatalf("contadores: %d liberadas, %d recusadas", l.allowed.Load(), l.denied.Load())
+	}
+}
+
+func TestAllowDisabledOrEmptyKey(t *testing.T) {
+	off, _ := testLimiter(Limit{})
+	on, _ := testLimiter(Limit{Rate: 1, Burst: 1})
+	for i := 0; i < 5; i++ {
+		if !off.Allow("u1") || !on.Allow("") {
+			t.Fatal("limite desligado ou chave vazia sempre passa")
+		}
+	}
+	var nilLimiter *Limiter
+	if !nilLimiter.Allow("u1") {
+		t.Fatal("limiter nil sempre passa")
+	}
+}
+
+func TestGCDropsRefilledBuckets(t *testing.T) {
+	l, now := testLimiter(Limit{Rate: 1, Burst: 10}) // 10s para encher
+	l.Allow("antigo")
+	*now = now.Add(5 * time.Second)
+	l.Allow("recente")
+	if l.Keys() != 2 {
+		t.Fatalf("esperava 2 chaves, há %d", l.Keys())
+	}
+
+	// A varredura roda no máximo uma vez por minuto
+	*now = now.Add(30 * time.Second)
+	l.Allow("recente")
+	if l.Keys() != 2 {
+		t.Fatalf("varredura antes de um minuto: %d chaves", l.Keys())
+	}
+
+	// Um minuto depois da primeira varredura: "antigo" já encheu, "recente" acabou de ser usado
+	*now = now.Add(30 * time.Second)
+	l.Allow("recente")
+	if l.Keys() != 1 {
+		t.Fatalf("bucket cheio deveria ser descartado: %d chaves", l.Keys())
+	}
+}
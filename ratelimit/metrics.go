@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*Limiter{}
+)
+
+func register(l *Limiter) {
+	registryMu.Lock()
+	registry[l.Name] = l
+	registryMu.Unlock()
+}
+
+// WriteMetrics escreve os contadores de todos os limiters no formato texto do Prometheus
+func WriteMetrics(w io.Writer) {
+	registryMu.Lock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	limiters := make([]*Limiter, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		limiters = append(limiters, registry[name])
+	}
+	registryMu.Unlock()
+
+	fmt.Fprintln(w, "# HELP klens_ratelimit_allowed_total Requisições liberadas pelo rate limit.")
+	fmt.Fprintln(w, "# TYPE klens_ratelimit_allowed_total counter")
+	for _, l := range limiters {
+		fmt.Fprintf(w, "klens_ratelimit_allowed_total{limiter=%q} %d\n", l.Name, l.allowed.Load())
+	}
+	fmt.Fprintln(w, "# HELP klens_ratelimit_denied_total Requisições recusadas pelo rate limit.")
+	fmt.Fprintln(w, "# TYPE klens_ratelimit_denied_total counter")
+	for _, l := range limiters {
+		fmt.Fprintf(w, "klens_ratelimit_denied_total{limiter=%q} %d\n", l.Name, l.denied.Load())
+	}
+	fmt.Fprintln(w, "# HELP klens_ratelimit_keys Chaves (usuários/IPs) com bucket ativo.")
+	fmt.Fprintln(w, "# TYPE klens_ratelimit_keys gauge")
+	for _, l := range limiters {
+		fmt.Fprintf(w, "klens_ratelimit_keys{limiter=%q} %d\n", l.Name, l.Keys())
+	}
+	fmt.Fprintln(w, "# HELP klens_ratelimit_rate Fichas por segundo configuradas (0 = desligado).")
+	fmt.Fprintln(w, "# TYPE klens_ratelimit_rate gauge")
+	for _, l := range limiters {
+		fmt.Fprintf(w, "klens_ratelimit_rate{limiter=%q} %g\n", l.Name, l.Limit.Rate)
+	}
+}
@@ -0,0 +1,27 @@
+package ratelimit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	l := New("metrics_teste", Limit{Rate: 1, Burst: 1})
+	l.Allow("u1")
+	l.Allow("u1")
+
+	var b bytes.Buffer
+	WriteMetrics(&b)
+	for _, want := range []string{
+		`klens_ratelimit_allowed_total{limiter="metrics_teste"} 1`,
+		`klens_ratelimit_denied_total{limiter="metrics_teste"} 1`,
+		`klens_ratelimit_keys{limiter="metrics_teste"} 1`,
+		`klens_ratelimit_rate{limiter="metrics_teste"} 1`,
+		"# TYPE klens_ratelimit_allowed_total counter",
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Fatalf("métricas sem %q:\n%s", want, b.String())
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package speaker
+
+import (
+	"sync"
+)
+
+// Cluster é um grupo de falas com voz parecida (idealmente um membro)
+type Cluster struct {
+	ID       int
+	centroid Embedding
+	count    int
+	votes    map[string]int // Nomes que o tradutor atribuiu às falas deste grupo
+}
+
+// Label é o nome mais votado para o grupo ("" se ainda não houver)
+func (c *Cluster) Label() string {
+	best, bestVotes := "", 0
+	for name, v := range c.votes {
+		if v > bestVotes || (v == bestVotes && name < best) {
+			best, bestVotes = name, v
+		}
+	}
+	return best
+}
+
+// Unknown é o grupo das falas sem voz reconhecida: embedding vazio ou, com os grupos
+// esgotados, uma voz que não se parece com nenhum deles
+const Unknown = -1
+
+// Clusterer agrupa falas de uma live online: cada embedding entra no grupo mais
+// parecido (acima de Threshold) ou abre um grupo novo, até MaxClusters.
+type Clusterer struct {
+	Threshold   float64
+	MaxClusters int
+
+	mu       sync.Mutex
+	clusters []*Cluster
+}
+
+func NewClusterer(threshold float64, maxClusters int) *Clusterer {
+	return &Clusterer{Threshold: threshold, MaxClusters: maxClusters}
+}
+
+// Assign coloca a fala num grupo e devolve o ID dele (Unknown se não couber em nenhum)
+func (c *Clusterer) Assign(e Embedding) int {
+	if len(e) == 0 {
+		return Unknown
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var best *Cluster
+	bestSim := -1.0
+	for _, cl := range c.clusters {
+		if sim := Similarity(cl.centroid, e); sim > bestSim {
+			best, bestSim = cl, sim
+		}
+	}
+	if best == nil || bestSim < c.Threshold {
+		// Forçar a voz no grupo mais próximo passaria o nome de outro membro para ela
+		if len(c.clusters) >= c.MaxClusters {
+			return Unknown
+		}
+		best = &Cluster{ID: len(c.clusters), centroid: append(Embedding(nil), e...), votes: map[string]int{}}
+		c.clusters = append(c.clusters, best)
+	} else {
+		// Média móvel do centróide
+		best.count++
+		for i := range best.centroid {
+			best.centroid[i] += (e[i] - best.centroid[i]) / float64(best.count+1)
+		}
+	}
+	return best.ID
+}
+
+// Vote registra que o tradutor chamou a fala do grupo por esse nome
+func (c *Clusterer) Vote(id int, name string) {
+	if id < 0 || name == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id < len(c.clusters) {
+		c.clusters[id].votes[name]++
+	}
+}
+
+// Label devolve o nome aprendido para o grupo
+func (c *Clusterer) Label(id int) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id < 0 || id >= len(c.clusters) {
+		return ""
+	}
+	return c.clusters[id].Label()
+}
@@ -0,0 +1,59 @@
+package speaker
+
+import "testing"
+
+func TestClustererGroupsSimilarVoices(t *testing.T) {
+	c := NewClusterer(0.9, 4)
+	a := c.Assign(Embedding{1, 0, 0})
+	if a != 0 {
+		t.Fatalf("primeira voz abre o grupo 0, veio %d", a)
+	}
+	if got := c.Assign(Embedding{0.98, 0.1, 0}); got != a {
+		t.Fatalf("voz parecida deveria cair no grupo %d, veio %d", a, got)
+	}
+	b := c.Assign(Embedding{0, 1, 0})
+	if b == a || b == Unknown {
+		t.Fatalf("voz diferente deveria abrir outro grupo, veio %d", b)
+	}
+	if got := c.Assign(nil); got != Unknown {
+		t.Fatalf("embedding vazio é Unknown, veio %d", got)
+	}
+}
+
+func TestClustererReturnsUnknownWhenFull(t *testing.T) {
+	c := NewClusterer(0.9, 2)
+	c.Assign(Embedding{1, 0, 0})
+	c.Assign(Embedding{0, 1, 0})
+	c.Vote(0, "Jimin")
+
+	// Sem espaço para um grupo novo, a terceira voz não herda o nome de ninguém
+	if got := c.Assign(Embedding{0.6, 0, 0.8}); got != Unknown {
+		t.Fatalf("voz nova com os grupos esgotados deveria ser Unknown, veio %d", got)
+	}
+	if got := c.Label(Unknown); got != "" {
+		t.Fatalf("Unknown não tem nome: %q", got)
+	}
+	// Vozes conhecidas continuam sendo reconhecidas
+	if got := c.Assign(Embedding{0.99, 0.05, 0}); got != 0 {
+		t.Fatalf("voz do grupo 0 deveria continuar no grupo 0, veio %d", got)
+	}
+}
+
+func TestClustererLabelIsMostVoted(t *testing.T) {
+	c := NewClusterer(0.9, 4)
+	id := c.Assign(Embedding{1, 0})
+	if c.Label(id) != "" {
+		t.Fatal("grupo sem votos não tem nome")
+	}
+	c.Vote(id, "V")
+	c.Vote(id, "RM")
+	c.Vote(id, "V")
+	c.Vote(id, "")         // Nome vazio não conta
+	c.Vote(Unknown, "Jin") // Nem voto em Unknown
+	if got := c.Label(id); got != "V" {
+		t.Fatalf("nome mais votado: %q", got)
+	}
+	if got := c.Label(7); got != "" {
+		t.Fatalf("grupo inexistente: %q", got)
+	}
+}
@@ -0,0 +1,198 @@
+package speaker
+
+import (
+	"math"
+)
+
+// Embedding é uma "impressão" grosseira da voz: média e desvio das energias por banda
+// (escala mel, 100-4000 Hz), normalizada para não depender do volume.
+// Não substitui um modelo de verificação de locutor, mas separa bem vozes de timbres diferentes.
+type Embedding []float64
+
+const (
+	targetRate = 16000
+	frameMs    = 25
+	hopMs      = 10
+	numBands   = 20
+	minHz      = 100.0
+	maxHz      = 4000.0
+)
+
+// Compute extrai o embedding de PCM16 little-endian intercalado.
+// Retorna nil se não houver fala suficiente (menos de ~0,3s com voz).
+func Compute(pcm []byte, sampleRate, channels int) Embedding {
+	samples := downmix(pcm, sampleRate, channels)
+	frame := targetRate * frameMs / 1000
+	hop := targetRate * hopMs / 1000
+	if len(samples) < frame {
+		return nil
+	}
+
+	bands := bandFrequencies()
+	window := hamming(frame)
+
+	// Energia de cada quadro, para descartar os silenciosos
+	var frames [][]float64
+	var energies []float64
+	buf := make([]float64, frame)
+	for start := 0; start+frame <= len(samples); start += hop {
+		var energy float64
+		for i := 0; i < frame; i++ {
+			buf[i] = samples[start+i] * window[i]
+			energy += buf[i] * buf[i]
+		}
+		feats := make([]float64, numBands)
+		for b, hz := range bands {
+			feats[b] = math.Log(goertzel(buf, hz) + 1e-9)
+		}
+		frames = append(frames, feats)
+		energies = append(energies, energy)
+	}
+
+	// Só os quadros acima de 30% da energia média contam como voz
+	var mean float64
+	for _, e := range energies {
+		mean += e
+	}
+	mean /= float64(len(energies))
+	var voiced [][]float64
+	for i, f := range frames {
+		if energies[i] > 0.3*mean {
+			voiced = append(voiced, f)
+		}
+	}
+	if len(voiced) < 30 {
+		return nil
+	}
+
+	emb := make(Embedding, 2*numBands)
+	for _, f := range voiced {
+		// Subtrai a média do quadro: tira o efeito do volume (ganho)
+		var avg float64
+		for _, v := range f {
+			avg += v
+		}
+		avg /= numBands
+		for b, v := range f {
+			emb[b] += v - avg
+		}
+	}
+	n := float64(len(voiced))
+	for b := 0; b < numBands; b++ {
+		emb[b] /= n
+	}
+	for _, f := range voiced {
+		var avg float64
+		for _, v := range f {
+			avg += v
+		}
+		avg /= numBands
+		for b, v := range f {
+			d := v - avg - emb[b]
+			emb[numBands+b] += d * d
+		}
+	}
+	for b := 0; b < numBands; b++ {
+		emb[numBands+b] = math.Sqrt(emb[numBands+b] / n)
+	}
+	return emb.normalized()
+}
+
+// Similarity é o cosseno entre dois embeddings (1 = mesma direção)
+func Similarity(a, b Embedding) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+func (e Embedding) normalized() Embedding {
+	var norm float64
+	for _, v := range e {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+	if norm == 0 {
+		return e
+	}
+	for i := range e {
+		e[i] /= norm
+	}
+	return e
+}
+
+// downmix junta os canais e reamostra (por média) para 16 kHz
+func downmix(pcm []byte, sampleRate, channels int) []float64 {
+	if channels < 1 {
+		channels = 1
+	}
+	frameBytes := 2 * channels
+	n := len(pcm) / frameBytes
+	mono := make([]float64, n)
+	for i := 0; i < n; i++ {
+		var sum float64
+		for c := 0; c < channels; c++ {
+			off := i*frameBytes + 2*c
+			sum += float64(int16(uint16(pcm[off]) | uint16(pcm[off+1])<<8))
+		}
+		mono[i] = sum / float64(channels) / 32768
+	}
+	if sampleRate <= targetRate {
+		return mono
+	}
+
+	ratio := float64(sampleRate) / targetRate
+	out := make([]float64, int(float64(n)/ratio))
+	for i := range out {
+		from, to := int(float64(i)*ratio), int(float64(i+1)*ratio)
+		if to > n {
+			to = n
+		}
+		var sum float64
+		for _, v := range mono[from:to] {
+			sum += v
+		}
+		out[i] = sum / float64(to-from)
+	}
+	return out
+}
+
+// bandFrequencies espaça as bandas igualmente na escala mel
+func bandFrequencies() []float64 {
+	mel := func(hz float64) float64 { return 2595 * math.Log10(1+hz/700) }
+	hz := func(m float64) float64 { return 700 * (math.Pow(10, m/2595) - 1) }
+	lo, hi := mel(minHz), mel(maxHz)
+	out := make([]float64, numBands)
+	for i := range out {
+		out[i] = hz(lo + (hi-lo)*float64(i)/float64(numBands-1))
+	}
+	return out
+}
+
+func hamming(n int) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 0.54 - 0.46*math.Cos(2*math.Pi*float64(i)/float64(n-1))
+	}
+	return w
+}
+
+// goertzel mede a potência do quadro numa frequência só (mais barato que uma FFT inteira)
+func goertzel(x []float64, hz float64) float64 {
+	coeff := 2 * math.Cos(2*math.Pi*hz/targetRate)
+	var s1, s2 float64
+	for _, v := range x {
+		s0 := v + coeff*s1 - s2
+		s2, s1 = s1, s0
+	}
+	return s1*s1 + s2*s2 - coeff*s1*s2
+}
@@ -0,0 +1,77 @@
+package speaker
+
+import (
+	"math"
+	"testing"
+)
+
+// voice sintetiza 1s de uma "voz": fundamental f0 com harmônicos decaindo conforme tilt
+func voice(f0, tilt, gain float64, sampleRate, channels int) []byte {
+	n := sampleRate
+	pcm := make([]byte, 0, n*channels*2)
+	for i := 0; i < n; i++ {
+		var v float64
+		for h := 1; float64(h)*f0 < 4000; h++ {
+			v += math.Pow(tilt, float64(h-1)) * math.Sin(2*math.Pi*f0*float64(h)*float64(i)/float64(sampleRate))
+		}
+		s := int16(math.Max(-32767, math.Min(32767, v*gain)))
+		for c := 0; c < channels; c++ {
+			pcm = append(pcm, byte(s), byte(uint16(s)>>8))
+		}
+	}
+	return pcm
+}
+
+func TestComputeSeparatesVoices(t *testing.T) {
+	low := Compute(voice(110, 0.8, 4000, 16000, 1), 16000, 1)
+	lowAgain := Compute(voice(112, 0.8, 4000, 16000, 1), 16000, 1)
+	high := Compute(voice(260, 0.4, 4000, 16000, 1), 16000, 1)
+	if low == nil || lowAgain == nil || high == nil {
+		t.Fatal("fala com voz deveria gerar embedding")
+	}
+	same, other := Similarity(low, lowAgain), Similarity(low, high)
+	if same <= other {
+		t.Fatalf("mesma voz (%.3f) deveria ser mais parecida que vozes diferentes (%.3f)", same, other)
+	}
+}
+
+func TestComputeIgnoresVolumeAndFormat(t *testing.T) {
+	base := Compute(voice(150, 0.7, 4000, 16000, 1), 16000, 1)
+	quiet := Compute(voice(150, 0.7, 800, 16000, 1), 16000, 1)
+	stereo48k := Compute(voice(150, 0.7, 4000, 48000, 2), 48000, 2)
+	if s := Similarity(base, quiet); s < 0.99 {
+		t.Fatalf("volume não deveria mudar a voz: similaridade %.3f", s)
+	}
+	if s := Similarity(base, stereo48k); s < 0.95 {
+		t.Fatalf("estéreo a 48 kHz deveria bater com mono a 16 kHz: similaridade %.3f", s)
+	}
+}
+
+func TestComputeNeedsEnoughSpeech(t *testing.T) {
+	if e := Compute(make([]byte, 100), 16000, 1); e != nil {
+		t.Fatal("áudio menor que um quadro não tem embedding")
+	}
+	short := voice(150, 0.7, 4000, 16000, 1)[:16000*2/10] // 100ms
+	if e := Compute(short, 16000, 1); e != nil {
+		t.Fatal("menos de 0,3s de voz não tem embedding")
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	cases := []struct {
+		a, b Embedding
+		want float64
+	}{
+		{Embedding{1, 0}, Embedding{2, 0}, 1},
+		{Embedding{1, 0}, Embedding{0, 1}, 0},
+		{Embedding{1, 0}, Embedding{-1, 0}, -1},
+		{Embedding{1, 0}, Embedding{1, 0, 0}, 0}, // Tamanhos diferentes
+		{Embedding{0, 0}, Embedding{1, 0}, 0},
+		{nil, nil, 0},
+	}
+	for _, tc := range cases {
+		if got := Similarity(tc.a, tc.b); math.Abs(got-tc.want) > 1e-9 {
+			t.Fatalf("Similarity(%v, %v) = %v, esperava %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package subtitle
+
+import (
+	"k-lens/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Cue é uma legenda com início e fim relativos a uma origem (live ou clipe)
+type Cue struct {
+	ID    uint          `json:"id"`
+	Start time.Duration `json:"-"`
+	End   time.Duration `json:"-"`
+	Text  string        `json:"text"`
+}
+
+// DefaultMaxDuration é quanto uma legenda fica na tela se a próxima demorar
+const DefaultMaxDuration = 4 * time.Second
+
+// minCueDuration evita legendas que piscam quando duas falas chegam juntas
+const minCueDuration = 500 * time.Millisecond
+
+// FromCaptions converte CaptionLog em cues. O CaptionLog só guarda o início:
+// o fim é o início da próxima legenda, limitado a maxDuration.
+func FromCaptions(captions []models.CaptionLog, maxDuration time.Duration) []Cue {
+	sorted := make([]models.CaptionLog, len(captions))
+	copy(sorted, captions)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Timestamp < sorted[j].Timestamp })
+
+	cues := make([]Cue, 0, len(sorted))
+	for i, c := range sorted {
+		if strings.TrimSpace(c.Text) == "" {
+			continue
+		}
+		text := strings.TrimSpace(c.Display())
+		start := time.Duration(c.Timestamp) * time.Millisecond
+		end := start + maxDuration
+		if i+1 < len(sorted) {
+			if next := time.Duration(sorted[i+1].Timestamp) * time.Millisecond; next < end {
+				end = next
+			}
+		}
+		if end-start < minCueDuration {
+			end = start + minCueDuration
+		}
+		cues = append(cues, Cue{ID: c.ID, Start: start, End: end, Text: text})
+	}
+	return cues
+}
+
+// Retime recorta as cues para a janela [from, to) e as desloca para começar em zero
+func Retime(cues []Cue, from, to time.Duration) []Cue {
+	var out []Cue
+	for _, c := range cues {
+		if c.End <= from || c.Start >= to {
+			continue
+		}
+		if c.Start < from {
+			c.Start = from
+		}
+		if c.End > to {
+			c.End = to
+		}
+		c.Start -= from
+		c.End -= from
+		out = append(out, c)
+	}
+	return out
+}
+
+// Merge junta legendas curtas com a seguinte quando o intervalo entre elas é pequeno,
+// evitando uma enxurrada de cues de meio segundo no player.
+func Merge(cues []Cue, shorterThan, maxGap, maxDuration time.Duration) []Cue {
+	if len(cues) == 0 {
+		return cues
+	}
+
+	out := []Cue{cues[0]}
+	for _, c := range cues[1:] {
+		last := &out[len(out)-1]
+		short := last.End-last.Start < shorterThan || c.End-c.Start < shorterThan
+		if short && c.Start-last.End <= maxGap && c.End-last.Start <= maxDuration {
+			last.End = c.End
+			last.Text = last.Text + " " + c.Text
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// ApplyRevisions aplica as correções dos moderadores: a revisão mais alta de cada
+// legenda substitui texto e locutor, e legendas retiradas saem da lista.
+func ApplyRevisions(captions []models.CaptionLog, revisions []models.CaptionRevision) []models.CaptionLog {
+	latest := map[uint]models.CaptionRevision{}
+	for _, r := range revisions {
+		if cur, ok := latest[r.CaptionLogID]; !ok || r.Revision > cur.Revision {
+			latest[r.CaptionLogID] = r
+		}
+	}
+
+	out := make([]models.CaptionLog, 0, len(captions))
+	for _, c := range captions {
+		if r, ok := latest[c.ID]; ok {
+			if r.Deleted {
+				continue
+			}
+			c.Text = r.Text
+			c.Speaker = r.Speaker
+		}
+		out = append(out, c)
+	}
+	return out
+}
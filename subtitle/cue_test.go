@@ -0,0 +1,89 @@
+package subtitle
+
+import (
+	"k-lens/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const ms = time.Millisecond
+
+func TestFromCaptionsDerivesEndTimes(t *testing.T) {
+	captions := []models.CaptionLog{
+		{ID: 4, Timestamp: 10000, Text: "quarta"},
+		{ID: 1, Timestamp: 1000, Text: "primeira", Speaker: "Jimin"},
+		{ID: 2, Timestamp: 2000, Text: "segunda"},
+		{ID: 3, Timestamp: 2200, Text: " terceira "},
+	}
+	want := []Cue{
+		{ID: 1, Start: 1000 * ms, End: 2000 * ms, Text: "[Jimin] primeira"}, // termina na próxima
+		{ID: 2, Start: 2000 * ms, End: 2500 * ms, Text: "segunda"},          // estica até o mínimo
+		{ID: 3, Start: 2200 * ms, End: 6200 * ms, Text: "terceira"},         // limitada a maxDuration
+		{ID: 4, Start: 10000 * ms, End: 14000 * ms, Text: "quarta"},
+	}
+	if got := FromCaptions(captions, DefaultMaxDuration); !reflect.DeepEqual(got, want) {
+		t.Fatalf("cues:\n%+v\nesperava\n%+v", got, want)
+	}
+}
+
+func TestFromCaptionsSkipsBlankText(t *testing.T) {
+	captions := []models.CaptionLog{{ID: 1, Timestamp: 0, Text: "  "}, {ID: 2, Timestamp: 1000, Text: "oi"}}
+	got := FromCaptions(captions, DefaultMaxDuration)
+	if len(got) != 1 || got[0].ID != 2 {
+		t.Fatalf("legenda vazia deveria ficar de fora: %+v", got)
+	}
+}
+
+func TestRetime(t *testing.T) {
+	cues := []Cue{
+		{ID: 1, Start: 0, End: 2000 * ms, Text: "a"},
+		{ID: 2, Start: 2000 * ms, End: 4000 * ms, Text: "b"},
+		{ID: 3, Start: 4000 * ms, End: 6000 * ms, Text: "c"},
+		{ID: 4, Start: 6000 * ms, End: 8000 * ms, Text: "d"},
+	}
+	want := []Cue{
+		{ID: 1, Start: 0, End: 1000 * ms, Text: "a"},
+		{ID: 2, Start: 1000 * ms, End: 3000 * ms, Text: "b"},
+		{ID: 3, Start: 3000 * ms, End: 4000 * ms, Text: "c"},
+	}
+	if got := Retime(cues, 1000*ms, 5000*ms); !reflect.DeepEqual(got, want) {
+		t.Fatalf("retime:\n%+v\nesperava\n%+v", got, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	cases := []struct {
+		name string
+		cues []Cue
+		want []Cue
+	}{
+		{
+			name: "curta junta com a seguinte",
+			cues: []Cue{{ID: 1, Start: 0, End: 300 * ms, Text: "a"}, {ID: 2, Start: 400 * ms, End: 2000 * ms, Text: "b"}},
+			want: []Cue{{ID: 1, Start: 0, End: 2000 * ms, Text: "a b"}},
+		},
+		{
+			name: "intervalo grande demais",
+			cues: []Cue{{ID: 1, Start: 0, End: 300 * ms, Text: "a"}, {ID: 2, Start: 1000 * ms, End: 2000 * ms, Text: "b"}},
+			want: []Cue{{ID: 1, Start: 0, End: 300 * ms, Text: "a"}, {ID: 2, Start: 1000 * ms, End: 2000 * ms, Text: "b"}},
+		},
+		{
+			name: "juntas passariam da duração máxima",
+			cues: []Cue{{ID: 1, Start: 0, End: 300 * ms, Text: "a"}, {ID: 2, Start: 400 * ms, End: 6000 * ms, Text: "b"}},
+			want: []Cue{{ID: 1, Start: 0, End: 300 * ms, Text: "a"}, {ID: 2, Start: 400 * ms, End: 6000 * ms, Text: "b"}},
+		},
+		{
+			name: "longas ficam separadas",
+			cues: []Cue{{ID: 1, Start: 0, End: 1500 * ms, Text: "a"}, {ID: 2, Start: 1600 * ms, End: 3000 * ms, Text: "b"}},
+			want: []Cue{{ID: 1, Start: 0, End: 1500 * ms, Text: "a"}, {ID: 2, Start: 1600 * ms, End: 3000 * ms, Text: "b"}},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Merge(tc.cues, time.Second, 500*ms, 5*time.Second); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("merge:\n%+v\nesperava\n%+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package subtitle
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteSRT grava as cues no formato SubRip
+func WriteSRT(w io.Writer, cues []Cue) error {
+	for i, c := range cues {
+		_, err := fmt.Fprintf(w, "%d\n%s --> %s\n%s\n\n", i+1, clock(c.Start, ","), clock(c.End, ","), cueText(c.Text))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteVTT grava as cues no formato WebVTT
+func WriteVTT(w io.Writer, cues []Cue) error {
+	if _, err := io.WriteString(w, "WEBVTT\n\n"); err != nil {
+		return err
+	}
+	for _, c := range cues {
+		_, err := fmt.Fprintf(w, "%s --> %s\n%s\n\n", clock(c.Start, "."), clock(c.End, "."), cueText(c.Text))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cueText troca o "-->" do texto, que quebraria o parser do player em SRT e WebVTT
+func cueText(text string) string {
+	return strings.ReplaceAll(text, "-->", "→")
+}
+
+// clock formata HH:MM:SS<sep>mmm
+func clock(d time.Duration, sep string) string {
+	if d < 0 {
+		d = 0
+	}
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// WriteTTML grava as cues em TTML (formato aceito por players de TV e plataformas de vídeo)
+func WriteTTML(w io.Writer, cues []Cue, lang string) error {
+	if lang == "" {
+		lang = "pt-BR"
+	}
+	header := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<tt xmlns="http://www.w3.org/ns/ttml" xml:lang="` + xmlEscape(lang) + `">` + "\n" +
+		"  <body>\n    <div>\n"
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	for _, c := range cues {
+		_, err := fmt.Fprintf(w, "      <p begin=\"%s\" end=\"%s\">%s</p>\n", clock(c.Start, "."), clock(c.End, "."), xmlEscape(c.Text))
+		if err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "    </div>\n  </body>\n</tt>\n")
+	return err
+}
+
+// WriteJSON grava as cues como lista JSON com tempos em milissegundos
+func WriteJSON(w io.Writer, cues []Cue) error {
+	type jsonCue struct {
+		ID      uint   `json:"id"`
+		StartMs int64  `json:"start_ms"`
+		EndMs   int64  `json:"end_ms"`
+		Text    string `json:"text"`
+	}
+	out := make([]jsonCue, 0, len(cues))
+	for _, c := range cues {
+		out = append(out, jsonCue{ID: c.ID, StartMs: c.Start.Milliseconds(), EndMs: c.End.Milliseconds(), Text: c.Text})
+	}
+	return json.NewEncoder(w).Encode(out)
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
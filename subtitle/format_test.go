@@ -0,0 +1,48 @@
+package subtitle
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+var golden = []Cue{
+	{ID: 1, Start: 1500 * time.Millisecond, End: 3 * time.Second, Text: "[RM] Olá, ARMY!"},
+	{ID: 2, Start: time.Hour + 2*time.Minute + 3*time.Second + 45*time.Millisecond, End: time.Hour + 2*time.Minute + 5*time.Second, Text: "antes --> depois & <fim>"},
+}
+
+func TestWriters(t *testing.T) {
+	cases := []struct {
+		name  string
+		write func(*bytes.Buffer) error
+		want  string
+	}{
+		{"SRT", func(b *bytes.Buffer) error { return WriteSRT(b, golden) }, "" +
+			"1\n00:00:01,500 --> 00:00:03,000\n[RM] Olá, ARMY!\n\n" +
+			"2\n01:02:03,045 --> 01:02:05,000\nantes → depois & <fim>\n\n"},
+		{"VTT", func(b *bytes.Buffer) error { return WriteVTT(b, golden) }, "WEBVTT\n\n" +
+			"00:00:01.500 --> 00:00:03.000\n[RM] Olá, ARMY!\n\n" +
+			"01:02:03.045 --> 01:02:05.000\nantes → depois & <fim>\n\n"},
+		{"TTML", func(b *bytes.Buffer) error { return WriteTTML(b, golden, "") }, "" +
+			`<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+			`<tt xmlns="http://www.w3.org/ns/ttml" xml:lang="pt-BR">` + "\n" +
+			"  <body>\n    <div>\n" +
+			"      <p begin=\"00:00:01.500\" end=\"00:00:03.000\">[RM] Olá, ARMY!</p>\n" +
+			"      <p begin=\"01:02:03.045\" end=\"01:02:05.000\">antes --&gt; depois &amp; &lt;fim&gt;</p>\n" +
+			"    </div>\n  </body>\n</tt>\n"},
+		{"JSON", func(b *bytes.Buffer) error { return WriteJSON(b, golden) }, "" +
+			`[{"id":1,"start_ms":1500,"end_ms":3000,"text":"[RM] Olá, ARMY!"},` +
+			`{"id":2,"start_ms":3723045,"end_ms":3725000,"text":"antes --\u003e depois \u0026 \u003cfim\u003e"}]` + "\n"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := tc.write(&b); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tc.want {
+				t.Fatalf("saída:\n%s\nesperava:\n%s", b.String(), tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package translate
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ContextTurn é uma fala anterior da live: o que foi dito (coreano) e a legenda final
+type ContextTurn struct {
+	Speaker    string `json:"speaker,omitempty"`
+	Transcript string `json:"transcript"`
+	Caption    string `json:"caption"`
+}
+
+// EstimateTokens aproxima o custo de um texto sem tokenizador: hangul ~1 token por
+// sílaba, texto latino ~1 token a cada 4 caracteres.
+func EstimateTokens(s string) int {
+	hangul, other := 0, 0
+	for _, r := range s {
+		if r >= 0xAC00 && r <= 0xD7A3 {
+			hangul++
+		} else {
+			other++
+		}
+	}
+	return hangul + (other+3)/4
+}
+
+func (t ContextTurn) tokens() int {
+	return EstimateTokens(t.Transcript) + EstimateTokens(t.Caption) + 4
+}
+
+// TrimContext mantém as falas mais recentes que cabem em maxTurns e no orçamento de tokens
+func TrimContext(turns []ContextTurn, maxTurns, budget int) []ContextTurn {
+	if maxTurns > 0 && len(turns) > maxTurns {
+		turns = turns[len(turns)-maxTurns:]
+	}
+	if budget <= 0 {
+		return turns
+	}
+	used := 0
+	for i := len(turns) - 1; i >= 0; i-- {
+		used += turns[i].tokens()
+		if used > budget {
+			return turns[i+1:]
+		}
+	}
+	return turns
+}
+
+// contextPrompt descreve as falas anteriores para manter pronomes e assunto
+func contextPrompt(turns []ContextTurn) string {
+	if len(turns) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("CONTEXTO (falas anteriores desta live, da mais antiga para a mais recente; não traduza de novo):\n")
+	for _, t := range turns {
+		b.WriteString("- ")
+		if t.Speaker != "" {
+			b.WriteString(t.Speaker + " | ")
+		}
+		if utf8.RuneCountInString(t.Transcript) > 0 {
+			fmt.Fprintf(&b, "ko: %s | ", t.Transcript)
+		}
+		fmt.Fprintf(&b, "legenda: %s\n", t.Caption)
+	}
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package translate
+
+import (
+	"context"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+// FakeService é um tradutor determinístico: mesma entrada, mesma saída.
+// Serve para rodar o studio sem credenciais e para testes.
+type FakeService struct{}
+
+func NewFakeService() *FakeService {
+	return &FakeService{}
+}
+
+func (s *FakeService) TranslateAudio(ctx context.Context, req AudioRequest) (*AudioResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sum := crc32.ChecksumIEEE(req.Audio)
+	result := &AudioResult{Captions: map[string]string{}, Transcript: fmt.Sprintf("[fake ko] #%08x", sum), Speaker: req.SpeakerHint}
+	// Sem palpite de voz, escolhe um membro do elenco de forma determinística
+	if result.Speaker == "" && len(req.Roster) > 0 {
+		result.Speaker = req.Roster[int(sum%uint32(len(req.Roster)))]
+	}
+	for _, lang := range targetsOf(req) {
+		result.Captions[lang] = fmt.Sprintf("[fake %s] fala de %d bytes (%s) #%08x", lang, len(req.Audio), req.MIMEType, sum)
+	}
+
+	// Simula o streaming: uma parcial por palavra, como o modelo faria
+	if req.OnPartial != nil {
+		words := map[string][]string{}
+		longest := 0
+		for lang, text := range result.Captions {
+			words[lang] = strings.Fields(text)
+			if len(words[lang]) > longest {
+				longest = len(words[lang])
+			}
+		}
+		for n := 1; n < longest; n++ {
+			partial := Partial{Captions: map[string]string{}, Speaker: result.Speaker}
+			for lang, w := range words {
+				partial.Captions[lang] = strings.Join(w[:min(n, len(w))], " ")
+			}
+			req.OnPartial(partial)
+		}
+	}
+	return result, nil
+}
+
+func (s *FakeService) TranslateText(ctx context.Context, text string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return "[ko] " + text, nil
+}
+
+func (s *FakeService) Close() {}
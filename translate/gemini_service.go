@@ -2,22 +2,29 @@ package translate
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
 	"cloud.google.com/go/vertexai/genai"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
 type GeminiService struct {
 	client *genai.Client
-	model  *genai.GenerativeModel
+	model  *genai.GenerativeModel // Texto livre (tradução reversa)
+	audio  *genai.GenerativeModel // Legendas: responde JSON com um texto por idioma
 }
 
 func NewGeminiService(ctx context.Context) (*GeminiService, error) {
 	// A Vertex AI exige o ID do projeto e a localização (ex: us-central1)
 	projectID := os.Getenv("GOOGLE_CLOUD_PROJECT")
 	location := os.Getenv("GOOGLE_CLOUD_LOCATION") // Ex: us-central1
+	if projectID == "" || location == "" {
+		return nil, fmt.Errorf("GOOGLE_CLOUD_PROJECT e GOOGLE_CLOUD_LOCATION são obrigatórias para o provedor gemini")
+	}
 
 	// O caminho para o arquivo JSON de credenciais que você vai gerar
 	credentialsFile := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
@@ -28,54 +35,149 @@ func NewGeminiService(ctx context.Context) (*GeminiService, error) {
 		return nil, fmt.Errorf("falha ao criar cliente Vertex AI: %v", err)
 	}
 
+	// Tradução reversa: texto livre, sem o formato JSON das legendas
 	model := client.GenerativeModel("gemini-2.0-flash-001")
-
-	// Instrução de Sistema Robusta (Chirp v2 style)
 	model.SystemInstruction = &genai.Content{
+		Parts: []genai.Part{genai.Text(
+			"Você é um tradutor especializado em lives de K-pop (K-LENS STUDIO). " +
+				"Responda apenas com o texto traduzido, sem explicações, aspas ou comentários.",
+		)},
+	}
+
+	// Uma chamada só para todos os idiomas: o modelo devolve {"speaker": "...", "ko": "...", "pt-BR": "...", "en": "..."}
+	audioModel := client.GenerativeModel("gemini-2.0-flash-001")
+	audioModel.SystemInstruction = &genai.Content{
 		Parts: []genai.Part{genai.Text(
 			"Você é um tradutor simultâneo especializado em lives de K-pop (K-LENS STUDIO). " +
-				"Sua tarefa é converter áudio coreano para português brasileiro natural. " +
+				"Sua tarefa é converter áudio coreano em legendas naturais nos idiomas pedidos. " +
+				"FORMATO DA RESPOSTA: sempre um único objeto JSON plano, sem markdown e sem texto fora dele. " +
+				"Todos os valores são strings (nunca null, números ou objetos). As chaves são: " +
+				`"speaker" (quem fala, vazio se não der para saber), ` +
+				`"ko" (transcrição em coreano) e uma chave por idioma de destino pedido na mensagem, com o código exato (ex: "pt-BR", "en"). ` +
 				"REGRAS CRÍTICAS: " +
-				"1. Se houver música predominante, responda apenas: [MÚSICA]. " +
-				"2. Se houver silêncio ou apenas ruído de fundo, responda: [SILÊNCIO]. " +
-				"3. Se houver fala, seja informal, use gírias do fandom (bias, comeback, etc). " +
-				"4. Seja extremamente conciso para caber em legendas rápidas.",
+				"1. Se houver música predominante, silêncio ou apenas ruído de fundo, devolva todas as chaves com string vazia \"\" " +
+				"(não escreva marcações como [MÚSICA] ou [SILÊNCIO]: legenda vazia não é exibida). " +
+				"2. Se houver fala, seja informal, use gírias do fandom (bias, comeback, etc). " +
+				"3. Seja extremamente conciso para caber em legendas rápidas.",
 		)},
 	}
+	audioModel.ResponseMIMEType = "application/json"
 
 	return &GeminiService{
 		client: client,
 		model:  model,
+		audio:  audioModel,
 	}, nil
 }
 
-func (s *GeminiService) TranslateAudio(ctx context.Context, audioData []byte) (string, error) {
+// TranslateAudio traduz um blob de áudio já encapsulado (WAV, webm, ogg ou FLAC)
+// para todos os idiomas pedidos numa única chamada.
+// O MIMEType deve corresponder ao container real, ex: "audio/wav".
+func (s *GeminiService) TranslateAudio(ctx context.Context, req AudioRequest) (*AudioResult, error) {
+	targets := targetsOf(req)
+
 	// Na Vertex AI, enviamos o blob de áudio como parte do conteúdo
 	prompt := []genai.Part{
 		genai.Blob{
-			MIMEType: "audio/webm",
-			Data:     audioData,
+			MIMEType: req.MIMEType,
+			Data:     req.Audio,
 		},
-		genai.Text("Traduza o áudio acima."),
+		genai.Text(contextPrompt(req.Context) + audioPrompt(targets) + "\n" +
+			speakerPrompt(req.Roster, req.SpeakerHint) + glossaryPrompt(req.Glossary, targets)),
 	}
 
-	resp, err := s.model.GenerateContent(ctx, prompt...)
-	if err != nil {
-		return "", fmt.Errorf("erro vertex ai audio: %v", err)
+	// Streaming: cada pedaço que chega vira legenda parcial
+	iter := s.audio.GenerateContentStream(ctx, prompt...)
+	var output strings.Builder
+	for {
+		resp, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("erro vertex ai audio: %v", err)
+		}
+		if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+			continue
+		}
+		for _, part := range resp.Candidates[0].Content.Parts {
+			if text, ok := part.(genai.Text); ok {
+				output.WriteString(string(text))
+			}
+		}
+		if req.OnPartial != nil {
+			if fields := partialCaptions(output.String()); len(fields) > 0 {
+				speaker := fields[speakerKey]
+				delete(fields, speakerKey)
+				delete(fields, transcriptKey)
+				req.OnPartial(Partial{Captions: fields, Speaker: speaker})
+			}
+		}
 	}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
-		return "", nil
+	return parseAudioResponse(output.String(), targets)
+}
+
+// parseAudioResponse lê o objeto JSON das legendas. O modelo nem sempre respeita
+// "só strings": null, número ou falta de chave vira texto vazio em vez de derrubar a fala.
+func parseAudioResponse(output string, targets []string) (*AudioResult, error) {
+	result := &AudioResult{Captions: map[string]string{}}
+	if strings.TrimSpace(output) == "" {
+		return result, nil
 	}
 
-	var output string
-	for _, part := range resp.Candidates[0].Content.Parts {
-		if text, ok := part.(genai.Text); ok {
-			output += string(text)
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(output), &fields); err != nil {
+		return nil, fmt.Errorf("resposta fora do formato JSON: %v", err)
+	}
+	text := func(key string) string {
+		s, _ := fields[key].(string)
+		return strings.TrimSpace(s)
+	}
+	for _, lang := range targets {
+		if t := text(lang); t != "" {
+			result.Captions[lang] = t
 		}
 	}
+	result.Transcript = text(transcriptKey)
+	result.Speaker = text(speakerKey)
+	return result, nil
+}
+
+// Chaves fixas da resposta JSON: quem fala (vem primeiro, para já aparecer nas parciais)
+// e a transcrição em coreano
+const (
+	speakerKey    = "speaker"
+	transcriptKey = "ko"
+)
 
-	return output, nil
+// audioPrompt pede um objeto JSON com o locutor, a transcrição e uma chave por idioma de destino
+func audioPrompt(targets []string) string {
+	var b strings.Builder
+	b.WriteString("Identifique quem fala, transcreva o áudio acima em coreano e traduza para: ")
+	keys := []string{fmt.Sprintf("%q: \"...\"", speakerKey), fmt.Sprintf("%q: \"...\"", transcriptKey)}
+	for i, lang := range targets {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s (%s)", lang, LanguageName(lang))
+		keys = append(keys, fmt.Sprintf("%q: \"...\"", lang))
+	}
+	b.WriteString(". Responda apenas com um objeto JSON: {" + strings.Join(keys, ", ") + "}.")
+	return b.String()
+}
+
+// speakerPrompt explica como preencher o "speaker"
+func speakerPrompt(roster []string, hint string) string {
+	var b strings.Builder
+	if len(roster) > 0 {
+		fmt.Fprintf(&b, "Em %q use exatamente um destes nomes: %s. ", speakerKey, strings.Join(roster, ", "))
+	}
+	if hint != "" {
+		fmt.Fprintf(&b, "Pela voz, o palpite é %s (confirme pelo contexto). ", hint)
+	}
+	fmt.Fprintf(&b, "Se não der para saber quem fala, deixe %q vazio.\n", speakerKey)
+	return b.String()
 }
 
 func (s *GeminiService) TranslateText(ctx context.Context, text string) (string, error) {
@@ -0,0 +1,36 @@
+package translate
+
+import "testing"
+
+func TestParseAudioResponseToleratesNonStringFields(t *testing.T) {
+	cases := []struct {
+		name    string
+		output  string
+		speaker string
+		pt      string
+	}{
+		{"speaker null", `{"speaker": null, "ko": "안녕", "pt-BR": "Oi gente", "en": "Hi"}`, "", "Oi gente"},
+		{"sem speaker", `{"ko": "안녕", "pt-BR": "Oi gente", "en": "Hi"}`, "", "Oi gente"},
+		{"speaker numérico", `{"speaker": 3, "ko": "안녕", "pt-BR": "Oi gente"}`, "", "Oi gente"},
+		{"idioma null", `{"speaker": "Jimin", "ko": "", "pt-BR": null, "en": "Hi"}`, "Jimin", ""},
+		{"música (tudo vazio)", `{"speaker": "", "ko": "", "pt-BR": "", "en": ""}`, "", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := parseAudioResponse(tc.output, []string{"pt-BR", "en"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Speaker != tc.speaker || result.Captions["pt-BR"] != tc.pt {
+				t.Fatalf("resultado %+v", result)
+			}
+			if _, ok := result.Captions["pt-BR"]; ok && tc.pt == "" {
+				t.Fatal("legenda vazia não deveria entrar no resultado")
+			}
+		})
+	}
+
+	if _, err := parseAudioResponse(`[MÚSICA]`, []string{"pt-BR"}); err == nil {
+		t.Fatal("resposta que não é JSON deveria dar erro")
+	}
+}
@@ -0,0 +1,154 @@
+package translate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GlossaryEntry é um termo com grafia obrigatória, enviado ao modelo e reforçado na saída
+type GlossaryEntry struct {
+	Source         string   `json:"source"`                // Forma original (coreano ou romanizada)
+	Translation    string   `json:"translation,omitempty"` // Grafia preferida; vazio = Source
+	Variants       []string `json:"variants,omitempty"`    // Grafias erradas que devem virar a preferida
+	DoNotTranslate bool     `json:"do_not_translate,omitempty"`
+	Language       string   `json:"language,omitempty"` // Só vale para este idioma (vazio = todos)
+}
+
+// Preferred é a grafia que deve aparecer na legenda
+func (e GlossaryEntry) Preferred() string {
+	if e.Translation != "" {
+		return e.Translation
+	}
+	return e.Source
+}
+
+// appliesTo indica se o termo vale para o idioma
+func (e GlossaryEntry) appliesTo(lang string) bool {
+	return e.Language == "" || e.Language == lang
+}
+
+type glossaryRule struct {
+	entry   GlossaryEntry
+	pattern *regexp.Regexp
+	longest int // Tamanho da forma mais longa do termo
+}
+
+// Glossary aplica o glossário nas legendas já traduzidas (pós-processamento)
+type Glossary struct {
+	Entries []GlossaryEntry
+	rules   []glossaryRule
+}
+
+// NewGlossary compila uma regra por termo: a forma original, as variantes e a
+// própria grafia preferida (para corrigir maiúsculas) viram a grafia preferida.
+func NewGlossary(entries []GlossaryEntry) *Glossary {
+	g := &Glossary{Entries: entries}
+	for _, e := range entries {
+		var alts []string
+		seen := map[string]bool{}
+		for _, f := range append([]string{e.Source, e.Preferred()}, e.Variants...) {
+			f = strings.TrimSpace(f)
+			if f == "" || seen[strings.ToLower(f)] {
+				continue
+			}
+			seen[strings.ToLower(f)] = true
+			alts = append(alts, f)
+		}
+		if len(alts) == 0 {
+			continue
+		}
+		// Formas mais longas primeiro, para "Park Jimin" ganhar de "Jimin"
+		sort.SliceStable(alts, func(i, j int) bool { return len(alts[i]) > len(alts[j]) })
+		longest := len(alts[0])
+		for i := range alts {
+			alts[i] = regexp.QuoteMeta(alts[i])
+		}
+		g.rules = append(g.rules, glossaryRule{entry: e, pattern: regexp.MustCompile(`(?i)` + strings.Join(alts, "|")), longest: longest})
+	}
+	// O mesmo vale entre termos: "박지민" é aplicado antes de "지민" comer o fim dele
+	sort.SliceStable(g.rules, func(i, j int) bool { return g.rules[i].longest > g.rules[j].longest })
+	return g
+}
+
+// Enforce troca as grafias erradas pela preferida nas legendas do idioma.
+// Formas latinas só valem como palavra inteira ("Suga" não mexe em "sugar");
+// formas em hangul casam mesmo com partícula colada ("지민이").
+func (g *Glossary) Enforce(text, lang string) string {
+	if g == nil {
+		return text
+	}
+	for _, r := range g.rules {
+		if !r.entry.appliesTo(lang) {
+			continue
+		}
+		preferred := r.entry.Preferred()
+
+		var b strings.Builder
+		last := 0
+		for _, loc := range r.pattern.FindAllStringIndex(text, -1) {
+			match := text[loc[0]:loc[1]]
+			if !hasHangul(match) && !(wordBoundaryBefore(text, loc[0]) && wordBoundaryAfter(text, loc[1])) {
+				continue
+			}
+			b.WriteString(text[last:loc[0]])
+			b.WriteString(preferred)
+			last = loc[1]
+		}
+		b.WriteString(text[last:])
+		text = b.String()
+	}
+	return text
+}
+
+func wordBoundaryBefore(s string, i int) bool {
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return i == 0 || !isWordRune(r)
+}
+
+func wordBoundaryAfter(s string, i int) bool {
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return i == len(s) || !isWordRune(r)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// glossaryPrompt descreve o glossário para o modelo, só com os termos dos idiomas pedidos
+func glossaryPrompt(entries []GlossaryEntry, targets []string) string {
+	var b strings.Builder
+	for _, e := range entries {
+		applies := false
+		for _, lang := range targets {
+			applies = applies || e.appliesTo(lang)
+		}
+		if !applies {
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString("GLOSSÁRIO OBRIGATÓRIO (use exatamente estas grafias):\n")
+		}
+		fmt.Fprintf(&b, "- %s → %s", e.Source, e.Preferred())
+		if e.DoNotTranslate {
+			b.WriteString(" (não traduzir)")
+		}
+		if e.Language != "" {
+			fmt.Fprintf(&b, " [só %s]", e.Language)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func hasHangul(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Hangul, r) {
+			return true
+		}
+	}
+	return false
+}
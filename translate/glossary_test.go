@@ -0,0 +1,43 @@
+package translate
+
+import "testing"
+
+func TestGlossaryEnforce(t *testing.T) {
+	g := NewGlossary([]GlossaryEntry{
+		{Source: "지민", Translation: "Jimin", Variants: []string{"Jimim", "Ji-min"}},
+		{Source: "박지민", Translation: "Park Jimin"},
+		{Source: "슈가", Translation: "Suga"},
+		{Source: "아미", Translation: "ARMY", Variants: []string{"Armys"}, Language: "en"},
+	})
+	cases := []struct {
+		name string
+		text string
+		lang string
+		want string
+	}{
+		{"variante vira a grafia preferida", "Oi, Jimim!", "pt-BR", "Oi, Jimin!"},
+		{"maiúsculas corrigidas", "o JIMIN chegou", "pt-BR", "o Jimin chegou"},
+		{"hífen na variante", "Ji-min cantou", "pt-BR", "Jimin cantou"},
+		{"palavra inteira: não mexe dentro de outra", "sugar e Jiminho", "pt-BR", "sugar e Jiminho"},
+		{"palavra inteira com pontuação", "(suga)", "pt-BR", "(Suga)"},
+		{"hangul com partícula colada", "지민이 왔어", "pt-BR", "Jimin이 왔어"},
+		{"hangul com partícula de tópico", "슈가는", "pt-BR", "Suga는"},
+		{"forma mais longa primeiro", "박지민 e 지민", "pt-BR", "Park Jimin e Jimin"},
+		{"termo só de um idioma", "Armys!", "en", "ARMY!"},
+		{"termo de outro idioma não se aplica", "Armys!", "pt-BR", "Armys!"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := g.Enforce(tc.text, tc.lang); got != tc.want {
+				t.Fatalf("Enforce(%q) = %q, esperava %q", tc.text, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGlossaryEnforceNil(t *testing.T) {
+	var g *Glossary
+	if got := g.Enforce("Jimim", "pt-BR"); got != "Jimim" {
+		t.Fatalf("glossário nil não mexe no texto: %q", got)
+	}
+}
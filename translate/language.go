@@ -0,0 +1,69 @@
+package translate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Idiomas de legenda suportados (códigos BCP 47)
+const (
+	LangPortuguese = "pt-BR"
+	LangEnglish    = "en"
+	LangSpanish    = "es"
+)
+
+// DefaultLanguage é o idioma de quem não escolheu nenhum
+const DefaultLanguage = LangPortuguese
+
+// languageNames descreve cada idioma para o modelo
+var languageNames = map[string]string{
+	LangPortuguese: "português brasileiro",
+	LangEnglish:    "inglês",
+	LangSpanish:    "espanhol",
+}
+
+// LanguageName devolve a descrição do idioma usada nos prompts
+func LanguageName(code string) string {
+	if name, ok := languageNames[code]; ok {
+		return name
+	}
+	return code
+}
+
+// NormalizeLanguage aceita variações comuns ("pt", "PT-br", "en-US") e devolve o código canônico
+func NormalizeLanguage(code string) (string, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	switch {
+	case code == "pt" || code == "pt-br":
+		return LangPortuguese, true
+	case code == "en" || strings.HasPrefix(code, "en-"):
+		return LangEnglish, true
+	case code == "es" || strings.HasPrefix(code, "es-"):
+		return LangSpanish, true
+	}
+	return "", false
+}
+
+// NormalizeLanguages valida e remove duplicados, mantendo a ordem (o primeiro é o idioma principal).
+// Lista vazia vira só o idioma padrão.
+func NormalizeLanguages(codes []string) ([]string, error) {
+	var out []string
+	seen := map[string]bool{}
+	for _, c := range codes {
+		if strings.TrimSpace(c) == "" {
+			continue
+		}
+		lang, ok := NormalizeLanguage(c)
+		if !ok {
+			return nil, fmt.Errorf("idioma não suportado: %q (use pt-BR, en ou es)", c)
+		}
+		if !seen[lang] {
+			seen[lang] = true
+			out = append(out, lang)
+		}
+	}
+	if len(out) == 0 {
+		out = []string{DefaultLanguage}
+	}
+	return out, nil
+}
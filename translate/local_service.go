@@ -0,0 +1,107 @@
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// LocalService fala com um modelo self-hosted via HTTP.
+// Contrato esperado do servidor:
+//
+//	POST {base}/translate/audio  {"audio": <base64>, "mime_type": "audio/wav", "targets": ["pt-BR", "en"],
+//	                              "glossary": [...], "context": [{"transcript": "...", "caption": "..."}],
+//	                              "roster": ["Jimin", "RM"], "hint": "Jimin"}
+//	                             -> {"texts": {"pt-BR": "...", "en": "..."}, "transcript": "...", "speaker": "Jimin"}
+//	                                (ou {"text": "..."} só com o primeiro idioma)
+//	POST {base}/translate/text   {"text": "..."}                                 -> {"text": "..."}
+type LocalService struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewLocalService(baseURL string) *LocalService {
+	return &LocalService{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (s *LocalService) TranslateAudio(ctx context.Context, req AudioRequest) (*AudioResult, error) {
+	targets := targetsOf(req)
+	// []byte vira base64 automaticamente no encoding/json
+	out, err := s.post(ctx, "/translate/audio", map[string]interface{}{
+		"audio":     req.Audio,
+		"mime_type": req.MIMEType,
+		"targets":   targets,
+		"glossary":  req.Glossary,
+		"context":   req.Context,
+		"roster":    req.Roster,
+		"hint":      req.SpeakerHint,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AudioResult{Captions: map[string]string{}, Transcript: out.Transcript, Speaker: out.Speaker}
+	for _, lang := range targets {
+		if text, ok := out.Texts[lang]; ok {
+			result.Captions[lang] = text
+		}
+	}
+	// Servidores antigos só devolvem "text": vale para o idioma principal
+	if len(result.Captions) == 0 && out.Text != "" {
+		result.Captions[targets[0]] = out.Text
+	}
+	return result, nil
+}
+
+func (s *LocalService) TranslateText(ctx context.Context, text string) (string, error) {
+	out, err := s.post(ctx, "/translate/text", map[string]interface{}{"text": text})
+	if err != nil {
+		return "", err
+	}
+	return out.Text, nil
+}
+
+func (s *LocalService) Close() {}
+
+type localResponse struct {
+	Text       string            `json:"text"`
+	Texts      map[string]string `json:"texts"`
+	Transcript string            `json:"transcript"`
+	Speaker    string            `json:"speaker"`
+}
+
+func (s *LocalService) post(ctx context.Context, path string, body interface{}) (*localResponse, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("erro no provedor local: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("provedor local respondeu %d em %s", resp.StatusCode, path)
+	}
+
+	var out localResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("resposta inválida do provedor local: %v", err)
+	}
+	return &out, nil
+}
@@ -0,0 +1,129 @@
+package translate
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Partial é o que já chegou do modelo enquanto ele ainda está gerando
+type Partial struct {
+	Captions map[string]string // Texto parcial por idioma
+	Speaker  string            // Quem fala, se o modelo já disse
+}
+
+// PartialFunc recebe cada legenda parcial
+type PartialFunc func(Partial)
+
+// partialCaptions extrai os pares "idioma": "texto" de um JSON ainda incompleto,
+// ex: `{"pt-BR": "Oi gente, hoje` -> {"pt-BR": "Oi gente, hoje"}.
+// Só olha objetos planos de strings, que é o formato pedido ao modelo.
+func partialCaptions(buf string) map[string]string {
+	out := map[string]string{}
+	var (
+		key    string
+		hasKey bool
+	)
+	for i := 0; i < len(buf); {
+		switch buf[i] {
+		case '"':
+			s, next, _ := readJSONString(buf, i+1)
+			if hasKey {
+				out[key] = s
+				hasKey = false
+			} else {
+				// Só é chave se vier um ":" depois (ou o buffer acabar antes)
+				j := next
+				for j < len(buf) && (buf[j] == ' ' || buf[j] == '\n' || buf[j] == '\t' || buf[j] == '\r') {
+					j++
+				}
+				if j < len(buf) && buf[j] == ':' {
+					key, hasKey = s, true
+					next = j + 1
+				}
+			}
+			i = next
+		case ' ', '\n', '\t', '\r', ':':
+			i++
+		default:
+			// Valor que não é string (null, número, objeto aninhado): a chave fica sem texto
+			hasKey = false
+			i++
+		}
+	}
+	return out
+}
+
+// readJSONString lê uma string JSON a partir de i (logo depois da aspa de abertura).
+// Retorna o texto, a posição depois da aspa de fechamento e se a string terminou.
+func readJSONString(buf string, i int) (string, int, bool) {
+	var b strings.Builder
+	for i < len(buf) {
+		c := buf[i]
+		switch {
+		case c == '"':
+			return b.String(), i + 1, true
+		case c == '\\':
+			if i+1 >= len(buf) {
+				return b.String(), len(buf), false
+			}
+			switch buf[i+1] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r', 'b', 'f':
+			case 'u':
+				if i+6 > len(buf) {
+					return b.String(), len(buf), false
+				}
+				r := hexRune(buf[i+2 : i+6])
+				i += 6
+				// Emoji e afins chegam como par de surrogates: "\ud83d\ude00"
+				if utf16.IsSurrogate(r) {
+					rest := buf[i:]
+					if len(rest) < 6 && (strings.HasPrefix(`\u`, rest) || strings.HasPrefix(rest, `\u`)) {
+						return b.String(), len(buf), false
+					}
+					if strings.HasPrefix(rest, `\u`) {
+						if pair := utf16.DecodeRune(r, hexRune(buf[i+2:i+6])); pair != utf8.RuneError {
+							r = pair
+							i += 6
+						}
+					}
+				}
+				b.WriteRune(r)
+				continue
+			default: // \" \\ \/
+				b.WriteByte(buf[i+1])
+			}
+			i += 2
+		default:
+			// Não corta um caractere UTF-8 pela metade no fim do buffer
+			r, size := utf8.DecodeRuneInString(buf[i:])
+			if r == utf8.RuneError && size <= 1 && !utf8.FullRuneInString(buf[i:]) {
+				return b.String(), len(buf), false
+			}
+			b.WriteString(buf[i : i+size])
+			i += size
+		}
+	}
+	return b.String(), len(buf), false
+}
+
+// hexRune lê os 4 dígitos hexadecimais de um escape \uXXXX
+func hexRune(hex string) rune {
+	var r rune
+	for _, h := range hex {
+		r <<= 4
+		switch {
+		case h >= '0' && h <= '9':
+			r |= h - '0'
+		case h >= 'a' && h <= 'f':
+			r |= h - 'a' + 10
+		case h >= 'A' && h <= 'F':
+			r |= h - 'A' + 10
+		}
+	}
+	return r
+}
@@ -0,0 +1,64 @@
+package translate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPartialCaptions(t *testing.T) {
+	cases := []struct {
+		name string
+		buf  string
+		want map[string]string
+	}{
+		{"vazio", ``, map[string]string{}},
+		{"só a chave aberta", `{"pt-`, map[string]string{}},
+		{"chave sem valor ainda", `{"pt-BR": `, map[string]string{}},
+		{"valor pela metade", `{"pt-BR": "Oi gente, hoje`, map[string]string{"pt-BR": "Oi gente, hoje"}},
+		{"segunda chave cortada", `{"pt-BR": "Oi", "e`, map[string]string{"pt-BR": "Oi"}},
+		{"objeto completo", `{"speaker": "RM", "pt-BR": "Oi", "en": "Hi"}`, map[string]string{"speaker": "RM", "pt-BR": "Oi", "en": "Hi"}},
+		{"escape de aspas e barra", `{"pt-BR": "ele disse \"oi\" \\o/"}`, map[string]string{"pt-BR": `ele disse "oi" \o/`}},
+		{"escape cortado no fim", `{"pt-BR": "linha\`, map[string]string{"pt-BR": "linha"}},
+		{"\\u cortado no fim", `{"pt-BR": "caf\u00`, map[string]string{"pt-BR": "caf"}},
+		{"\\u completo", `{"pt-BR": "caf\u00e9"}`, map[string]string{"pt-BR": "café"}},
+		{"par de surrogates", `{"pt-BR": "oi \ud83d\ude00!"}`, map[string]string{"pt-BR": "oi 😀!"}},
+		{"par de surrogates cortado", `{"pt-BR": "oi \ud83d\ude`, map[string]string{"pt-BR": "oi "}},
+		{"surrogate alto no fim", `{"pt-BR": "oi \ud83d`, map[string]string{"pt-BR": "oi "}},
+		{"surrogate sem par", `{"pt-BR": "oi \ud83d!"}`, map[string]string{"pt-BR": "oi \uFFFD!"}},
+		{"UTF-8 cortado no meio", "{\"pt-BR\": \"지민\xec\x9d", map[string]string{"pt-BR": "지민"}},
+		{"valor null não engole a próxima chave", `{"speaker": null, "pt-BR": "Oi"}`, map[string]string{"pt-BR": "Oi"}},
+		{"objeto aninhado", `{"captions": {"pt-BR": "Oi", "en": "Hi"}, "speaker": "V"}`, map[string]string{"pt-BR": "Oi", "en": "Hi", "speaker": "V"}},
+		{"número antes de texto", `{"n": 3, "pt-BR": "Oi"}`, map[string]string{"pt-BR": "Oi"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := partialCaptions(tc.buf); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("partialCaptions(%q) = %q, esperava %q", tc.buf, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadJSONString(t *testing.T) {
+	cases := []struct {
+		buf      string
+		text     string
+		next     int
+		complete bool
+	}{
+		{`abc" resto`, "abc", 4, true},
+		{`a\nb"`, "a\nb", 5, true},
+		{`abc`, "abc", 3, false},
+		{`ab\`, "ab", 3, false},
+		{`\ud83d\ude00"`, "😀", 13, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.buf, func(t *testing.T) {
+			text, next, complete := readJSONString(tc.buf, 0)
+			if text != tc.text || next != tc.next || complete != tc.complete {
+				t.Fatalf("readJSONString(%q) = %q, %d, %v; esperava %q, %d, %v",
+					tc.buf, text, next, complete, tc.text, tc.next, tc.complete)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package translate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Translator é o contrato que o handler usa para legendar a live,
+// independente de qual modelo está por trás.
+type Translator interface {
+	// TranslateAudio converte uma fala (coreano) em legendas, uma por idioma pedido
+	TranslateAudio(ctx context.Context, req AudioRequest) (*AudioResult, error)
+	// TranslateText faz a tradução reversa de comentários (pt-BR -> coreano)
+	TranslateText(ctx context.Context, text string) (string, error)
+	Close()
+}
+
+// AudioRequest é uma fala a traduzir
+type AudioRequest struct {
+	Audio    []byte
+	MIMEType string   // Container real do áudio, ex: "audio/wav"
+	Targets  []string // Idiomas de destino (vazio = DefaultLanguage)
+
+	// Glossary lista os termos do fandom com grafia obrigatória (vai no prompt)
+	Glossary []GlossaryEntry
+
+	// Context traz as últimas falas da live (já cortadas pelo orçamento de tokens)
+	Context []ContextTurn
+
+	// Roster lista os membros que o modelo pode atribuir à voz.
+	// SpeakerHint é o palpite do agrupamento local de vozes (vazio = sem palpite).
+	Roster      []string
+	SpeakerHint string
+
+	// OnPartial, se definido, recebe o texto parcial de cada idioma enquanto o modelo gera.
+	// Provedores sem streaming simplesmente não chamam; o resultado final vem no retorno.
+	OnPartial PartialFunc
+}
+
+// AudioResult traz uma legenda por idioma de destino e a transcrição em coreano
+type AudioResult struct {
+	Captions   map[string]string
+	Transcript string // O que foi dito, em coreano (vai para o contexto das próximas falas)
+	Speaker    string // Quem falou, segundo o modelo (vazio = não soube dizer)
+}
+
+// targetsOf garante pelo menos o idioma padrão
+func targetsOf(req AudioRequest) []string {
+	if len(req.Targets) == 0 {
+		return []string{DefaultLanguage}
+	}
+	return req.Targets
+}
+
+var (
+	_ Translator = (*GeminiService)(nil)
+	_ Translator = (*FakeService)(nil)
+	_ Translator = (*LocalService)(nil)
+)
+
+// Provedores disponíveis via TRANSLATE_PROVIDER
+const (
+	ProviderGemini = "gemini"
+	ProviderFake   = "fake"
+	ProviderLocal  = "local"
+)
+
+// NewFromEnv escolhe o provedor pela variável TRANSLATE_PROVIDER (padrão: gemini).
+// "fake" roda offline e em testes; "local" fala com um modelo próprio em LOCAL_TRANSLATE_URL.
+func NewFromEnv(ctx context.Context) (Translator, error) {
+	provider := strings.ToLower(os.Getenv("TRANSLATE_PROVIDER"))
+	if provider == "" {
+		provider = ProviderGemini
+	}
+
+	switch provider {
+	case ProviderGemini:
+		return NewGeminiService(ctx)
+	case ProviderFake:
+		return NewFakeService(), nil
+	case ProviderLocal:
+		baseURL := os.Getenv("LOCAL_TRANSLATE_URL")
+		if baseURL == "" {
+			return nil, fmt.Errorf("LOCAL_TRANSLATE_URL não definida para o provedor local")
+		}
+		return NewLocalService(baseURL), nil
+	default:
+		return nil, fmt.Errorf("provedor de tradução desconhecido: %s", provider)
+	}
+}